@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// Config provides string options (revel.Config)
+type Config interface {
+	String(option string) (string, bool)
+}
+
+// Key represents a JWT key identified by the kid header
+type Key struct {
+	// ID is the kid header value
+	ID string
+	// Method is the signing algorithm (HS256, RS256, EdDSA...)
+	Method jwt.SigningMethod
+	// SignKey is the HMAC secret or the private key, nil for verification only keys
+	SignKey interface{}
+	// VerifyKey is the HMAC secret or the public key
+	VerifyKey interface{}
+	// Ephemeral keys are generated on start as the secret is not configured
+	Ephemeral bool
+}
+
+// Keyring holds active JWT keys, tokens are signed with the current key
+// and verified with any active key, retired keys are rejected
+type Keyring struct {
+	// Current is the kid of the signing key
+	Current string
+	keys    map[string]*Key
+	retired map[string]bool
+}
+
+// DefaultKeyring is used for auth tokens, loaded from app.conf on start
+var DefaultKeyring = NewKeyring()
+
+// NewKeyring returns an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string]*Key{}, retired: map[string]bool{}}
+}
+
+// Add adds an active key, the first added key becomes current
+func (k *Keyring) Add(key *Key) {
+	k.keys[key.ID] = key
+	if k.Current == "" {
+		k.Current = key.ID
+	}
+}
+
+// Retire rejects tokens with the kid
+func (k *Keyring) Retire(kid string) {
+	delete(k.keys, kid)
+	k.retired[kid] = true
+}
+
+// Keys returns active keys
+func (k *Keyring) Keys() []*Key {
+	var res []*Key
+	for _, key := range k.keys {
+		res = append(res, key)
+	}
+	return res
+}
+
+// Sign returns the token signed with the current key
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	key, found := k.keys[k.Current]
+	if !found || key.SignKey == nil {
+		return "", fmt.Errorf("No signing key")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.SignKey)
+}
+
+// Parse verifies the token with the key from the kid header and returns the claims
+func (k *Keyring) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if k.retired[kid] {
+			return nil, fmt.Errorf("Retired key: %s", kid)
+		}
+		key, found := k.keys[kid]
+		if !found {
+			return nil, fmt.Errorf("Unknown key: %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.VerifyKey, nil
+	})
+	if err != nil {
+		return jwt.MapClaims{}, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return jwt.MapClaims{}, fmt.Errorf("Invalid token")
+	}
+	return claims, nil
+}
+
+// LoadKeyring loads keys configured as:
+//
+//	jwt.keys = kid1,kid2            active keys
+//	jwt.keys.current = kid1         signing key (the first active key by default)
+//	jwt.keys.retired = kid0         rejected keys
+//	jwt.key.<kid>.alg = HS256       HS256, HS384, HS512, RS256, RS384, RS512 or EdDSA
+//	jwt.key.<kid>.secret = ...      HMAC secret
+//	jwt.key.<kid>.private = ...     PEM private key file (relative to basePath)
+//	jwt.key.<kid>.public = ...      PEM public key file, derived from the private key if not set
+//
+// HMAC keys with empty secrets are generated randomly (ephemeral).
+func LoadKeyring(cfg Config, basePath string) (*Keyring, error) {
+	k := NewKeyring()
+	list := func(option string) []string {
+		value, _ := cfg.String(option)
+		var res []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+		return res
+	}
+
+	for _, kid := range list("jwt.keys.retired") {
+		k.Retire(kid)
+	}
+	for _, kid := range list("jwt.keys") {
+		if k.retired[kid] {
+			return nil, fmt.Errorf("Key %s is both active and retired", kid)
+		}
+		key, err := loadKey(cfg, basePath, kid)
+		if err != nil {
+			return nil, fmt.Errorf("Key %s: %v", kid, err)
+		}
+		k.Add(key)
+	}
+
+	if current, found := cfg.String("jwt.keys.current"); found && current != "" {
+		if _, found := k.keys[current]; !found {
+			return nil, fmt.Errorf("Current key %s is not active", current)
+		}
+		k.Current = current
+	}
+	if key, found := k.keys[k.Current]; !found || key.SignKey == nil {
+		return nil, fmt.Errorf("No signing key")
+	}
+	return k, nil
+}
+
+// loadKey loads the key options
+func loadKey(cfg Config, basePath, kid string) (*Key, error) {
+	option := func(name string) string {
+		value, _ := cfg.String("jwt.key." + kid + "." + name)
+		return value
+	}
+	readPEM := func(name string) ([]byte, error) {
+		path := option(name)
+		if path == "" {
+			return nil, nil
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(basePath, path)
+		}
+		return os.ReadFile(path)
+	}
+
+	alg := option("alg")
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+	key := &Key{ID: kid, Method: jwt.GetSigningMethod(alg)}
+	if key.Method == nil {
+		return nil, fmt.Errorf("Unknown algorithm %s", alg)
+	}
+
+	privatePEM, err := readPEM("private")
+	if err != nil {
+		return nil, err
+	}
+	publicPEM, err := readPEM("public")
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		secret := []byte(option("secret"))
+		if len(secret) == 0 {
+			secret = make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+			key.Ephemeral = true
+		}
+		key.SignKey, key.VerifyKey = secret, secret
+
+	case *jwt.SigningMethodRSA:
+		if privatePEM != nil {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.SignKey, key.VerifyKey = privateKey, &privateKey.PublicKey
+		}
+		if publicPEM != nil {
+			if key.VerifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM); err != nil {
+				return nil, err
+			}
+		}
+
+	case *signingMethodEdDSA:
+		if privatePEM != nil {
+			privateKey, err := parseEdPrivateKey(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.SignKey, key.VerifyKey = privateKey, privateKey.Public()
+		}
+		if publicPEM != nil {
+			if key.VerifyKey, err = parseEdPublicKey(publicPEM); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("Unsupported algorithm %s", alg)
+	}
+
+	if key.VerifyKey == nil {
+		return nil, fmt.Errorf("Missing %s key file", alg)
+	}
+	return key, nil
+}
+
+// parseEdPrivateKey parses PKCS #8 PEM encoded Ed25519 private key
+func parseEdPrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Incorrect PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Not an Ed25519 private key")
+	}
+	return privateKey, nil
+}
+
+// parseEdPublicKey parses PKIX PEM encoded Ed25519 public key
+func parseEdPublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Incorrect PEM")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Not an Ed25519 public key")
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-gorp/gorp"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Attempts represents counted login attempts of a key (username or client IP),
+// successful attempts are uncounted
+type Attempts struct {
+	Failures int
+	Last     time.Time
+}
+
+// AttemptStore keeps the limiter state
+type AttemptStore interface {
+	// Get returns the key attempts, zero value if not found
+	Get(key string) (Attempts, error)
+	// Increment atomically counts an attempt of the key at now, attempts older than window are forgotten,
+	// returns the counted attempts
+	Increment(key string, now time.Time, window time.Duration) (Attempts, error)
+	// Decrement uncounts an attempt of the key, the last attempt time is kept
+	Decrement(key string) error
+	// Delete clears the key attempts
+	Delete(key string) error
+}
+
+// Throttle represents failed attempts backoff policy
+type Throttle struct {
+	// Free is the number of failures without delays
+	Free int
+	// Lockout is the number of failures locking the key for LockoutTime (0 - never locked)
+	Lockout int
+}
+
+// Limiter delays login attempts exponentially after failures and locks keys temporarily,
+// failures older than the lockout time are forgotten
+type Limiter struct {
+	Store AttemptStore
+	// Delay after the first non-free failure, doubled on each next failure up to MaxDelay
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// LockoutTime is the lock duration, also the failures memory
+	LockoutTime time.Duration
+}
+
+// Login attempts policies by usernames and client IPs, overridden from app.conf on start
+var (
+	UsernameThrottle = Throttle{Free: 3, Lockout: 10}
+	IPThrottle       = Throttle{Free: 20, Lockout: 100}
+)
+
+// DefaultLimiter is used for login attempts, configured from app.conf on start
+var DefaultLimiter = &Limiter{
+	Store:       NewMemoryStore(15 * time.Minute),
+	Delay:       time.Second,
+	MaxDelay:    time.Minute,
+	LockoutTime: 15 * time.Minute,
+}
+
+// Attempt counts an attempt of the key before the credentials check, returns the time left
+// until the attempt is allowed (0 - allowed, counted). Concurrent attempts are counted atomically,
+// only the allowed number of them pass. Successful attempts should be Reset or Released
+func (l *Limiter) Attempt(key string, throttle Throttle, now time.Time) (time.Duration, error) {
+	seen, err := l.Store.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if wait := l.wait(seen, throttle, now); wait > 0 {
+		return wait, nil
+	}
+
+	attempts, err := l.Store.Increment(key, now, l.LockoutTime)
+	if err != nil {
+		return 0, err
+	}
+	if attempts.Failures - 1 == seen.Failures {
+		return 0, nil
+	}
+	// attempts counted concurrently since the check are the latest ones
+	wait := l.wait(Attempts{Failures: attempts.Failures - 1, Last: now}, throttle, now)
+	if wait > 0 {
+		err = l.Store.Decrement(key)
+	}
+	return wait, err
+}
+
+// wait returns the time left until the next attempt is allowed after the attempts
+func (l *Limiter) wait(attempts Attempts, throttle Throttle, now time.Time) time.Duration {
+	if attempts.Failures <= throttle.Free || now.Sub(attempts.Last) >= l.LockoutTime {
+		return 0
+	}
+
+	delay := l.LockoutTime
+	if throttle.Lockout == 0 || attempts.Failures < throttle.Lockout {
+		exp := math.Min(float64(attempts.Failures - throttle.Free - 1), 30)
+		delay = time.Duration(math.Min(float64(l.Delay) * math.Pow(2, exp), float64(l.MaxDelay)))
+	}
+	if wait := attempts.Last.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Release uncounts a successful attempt of the key, keeping the other attempts
+func (l *Limiter) Release(key string) error {
+	return l.Store.Decrement(key)
+}
+
+// Reset clears failed attempts of the key
+func (l *Limiter) Reset(key string) error {
+	return l.Store.Delete(key)
+}
+
+// memoryStore keeps attempts in memory, the state is lost on restart and is not shared between instances
+type memoryStore struct {
+	sync.Mutex
+	attempts map[string]Attempts
+	// entries older than ttl are dropped on cleanup
+	ttl time.Duration
+}
+
+// memory store size triggering stale entries cleanup
+const memoryStoreCleanup = 10000
+
+// NewMemoryStore returns in-memory attempts store, entries older than ttl are dropped
+func NewMemoryStore(ttl time.Duration) AttemptStore {
+	return &memoryStore{attempts: map[string]Attempts{}, ttl: ttl}
+}
+
+func (s *memoryStore) Get(key string) (Attempts, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *memoryStore) Increment(key string, now time.Time, window time.Duration) (Attempts, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.attempts) >= memoryStoreCleanup {
+		for k, a := range s.attempts {
+			if now.Sub(a.Last) >= s.ttl {
+				delete(s.attempts, k)
+			}
+		}
+	}
+	attempts := s.attempts[key]
+	if now.Sub(attempts.Last) >= window {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.Last = now
+	s.attempts[key] = attempts
+	return attempts, nil
+}
+
+func (s *memoryStore) Decrement(key string) error {
+	s.Lock()
+	defer s.Unlock()
+	if attempts, found := s.attempts[key]; found && attempts.Failures > 0 {
+		attempts.Failures--
+		s.attempts[key] = attempts
+	}
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// dbStore keeps attempts in the LoginAttempt table
+type dbStore struct {
+	db gorp.SqlExecutor
+}
+
+// NewDBStore returns attempts store on the database, shared between instances
+func NewDBStore(db gorp.SqlExecutor) AttemptStore {
+	return &dbStore{db: db}
+}
+
+func (s *dbStore) Get(key string) (Attempts, error) {
+	var attempt models.LoginAttempt
+	err := s.db.SelectOne(&attempt, "SELECT * FROM LoginAttempt WHERE Subject = ?", key)
+	if err == sql.ErrNoRows {
+		return Attempts{}, nil
+	}
+	if err != nil {
+		return Attempts{}, err
+	}
+	return Attempts{Failures: attempt.Failures, Last: time.Unix(0, attempt.Last * int64(time.Millisecond))}, nil
+}
+
+func (s *dbStore) Increment(key string, now time.Time, window time.Duration) (Attempts, error) {
+	// a single upsert statement is atomic, sqlite serializes writers. The count read back may include
+	// attempts of other instances counted meanwhile, which only delays this attempt
+	last := now.UnixNano() / int64(time.Millisecond)
+	_, err := s.db.Exec(`INSERT INTO LoginAttempt (Subject, Failures, Last) VALUES (?, 1, ?)
+		ON CONFLICT (Subject) DO UPDATE SET
+			Failures = CASE WHEN Last <= ? THEN 1 ELSE Failures + 1 END,
+			Last = excluded.Last`,
+		key, last, last - int64(window / time.Millisecond))
+	if err != nil {
+		return Attempts{}, err
+	}
+	return s.Get(key)
+}
+
+func (s *dbStore) Decrement(key string) error {
+	_, err := s.db.Exec("UPDATE LoginAttempt SET Failures = Failures - 1 WHERE Subject = ? AND Failures > 0", key)
+	return err
+}
+
+func (s *dbStore) Delete(key string) error {
+	_, err := s.db.Exec("DELETE FROM LoginAttempt WHERE Subject = ?", key)
+	return err
+}
@@ -0,0 +1,35 @@
+package auth
+
+import "strings"
+
+// OAuth2 scopes declared in the swagger meta
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// Scopes lists known scopes
+var Scopes = []string{ScopeRead, ScopeWrite}
+
+// DefaultScope is granted to login tokens
+var DefaultScope = JoinScope(Scopes)
+
+// ParseScope splits the space-delimited scope claim
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// JoinScope returns the space-delimited scope claim
+func JoinScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// HasScope checks if the space-delimited scope claim contains the scope
+func HasScope(scope, required string) bool {
+	for _, s := range ParseScope(scope) {
+		if s == required {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// Token lifetimes, overridden from app.conf on start
+var (
+	// AccessTokenTTL is the JWT auth token lifetime
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is the refresh token lifetime, each refresh issues a new token
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// PersonalTokenTTL is the max (and default) personal access token lifetime
+	PersonalTokenTTL = 90 * 24 * time.Hour
+)
+
+// NewTokenID returns a random ID for the jti claim and refresh token families
+func NewTokenID() string {
+	return randomString(16)
+}
+
+// NewRefreshToken returns a random refresh token and its hash to store
+func NewRefreshToken() (token, hash string) {
+	token = randomString(32)
+	return token, HashToken(token)
+}
+
+// HashToken returns the hex SHA-256 of the refresh token or API key
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int) string {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// APIKeyPrefix starts API keys to recognize them
+const APIKeyPrefix = "mzk_"
+
+// NewAPIKey returns a random API key and its hash to store
+func NewAPIKey() (key, hash string) {
+	key = APIKeyPrefix + randomString(32)
+	return key, HashToken(key)
+}
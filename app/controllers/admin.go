@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/revel/revel"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mkulish/mazes/app/auth"
+	"github.com/mkulish/mazes/app/models"
+)
+
+var (
+	// likeEscaper escapes LIKE pattern wildcards
+	likeEscaper = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+
+	// adminUserColumns are user columns with personal mazes count
+	adminUserColumns = []string{"ID", "Username", "Role", "Disabled", "Created",
+		"(SELECT COUNT(*) FROM Maze WHERE Maze.OwnerID = User.ID AND Maze.OrgID = 0) AS Mazes"}
+)
+
+// Admin controller, the actions require the admin role
+type Admin struct {
+	App
+}
+
+// Users performs users search
+// swagger:route GET /admin/users admin searchUsers
+//
+// Search all users by username with cursor-based pagination (admins only)
+//
+//     Parameters:
+//     + name: q
+//       in: query
+//       description: username substring
+//       type: string
+//       example: mku
+//     + name: disabled
+//       in: query
+//       description: disabled or active users only
+//       type: boolean
+//     + name: limit
+//       in: query
+//       description: page size (50 by default, up to 500)
+//       type: integer
+//       example: 50
+//     + name: cursor
+//       in: query
+//       description: next page cursor from the previous page response
+//       type: integer
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: AdminUserListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Admin) Users(q, disabled string, limit int, cursor int64) revel.Result {
+	c.Validation.Range(limit, 0, models.MaxSearchLimit).Key("limit")
+	if disabled != "" && disabled != "true" && disabled != "false" {
+		c.Validation.Error("Should be one of: true, false").Key("disabled")
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	if limit == 0 {
+		limit = models.DefaultSearchLimit
+	}
+	query := c.Db.SqlStatementBuilder.Select(adminUserColumns...).From("User").Where("ID>?", cursor)
+	if q != "" {
+		query = query.Where("Username LIKE ? ESCAPE '\\'", "%" + likeEscaper.Replace(q) + "%")
+	}
+	if disabled != "" {
+		query = query.Where("Disabled=?", disabled == "true")
+	}
+
+	users := []*models.AdminUser{}
+	if _, err := c.Txn.Select(&users, query.OrderBy("ID").Limit(uint64(limit + 1))); err != nil {
+		c.Log.Errorf("users search: %v", err)
+		return c.internalError()
+	}
+
+	var next int64
+	if len(users) > limit {
+		users = users[:limit]
+		next = users[limit - 1].ID
+	}
+	return c.RenderJSON(models.AdminUserListResponse{OK: true, Items: users, NextCursor: next})
+}
+
+// DisableUser disables the user
+// swagger:route POST /admin/users/{userId}/disable admin disableUser
+//
+// Disables the user (admins only): login is rejected, auth, refresh and personal access
+// tokens are revoked, API keys are rejected until the user is enabled
+//
+//     Parameters:
+//     + name: userId
+//       in: path
+//       description: User id
+//       required: true
+//       type: integer
+//       example: 2
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: AdminUserResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Admin) DisableUser(id int64) revel.Result {
+	user, res := c.adminGetUser(id)
+	if res != nil {
+		return res
+	}
+	if user.Role == models.RoleAdmin {
+		c.Validation.Error("Admins can't be disabled").Key("id")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	user.Disabled = true
+	user.TokenVersion++
+	return c.updateUser(user, models.AuditUserDisable)
+}
+
+// EnableUser enables the user
+// swagger:route POST /admin/users/{userId}/enable admin enableUser
+//
+// Enables the disabled user (admins only)
+//
+//     Parameters:
+//     + name: userId
+//       in: path
+//       description: User id
+//       required: true
+//       type: integer
+//       example: 2
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: AdminUserResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Admin) EnableUser(id int64) revel.Result {
+	user, res := c.adminGetUser(id)
+	if res != nil {
+		return res
+	}
+
+	user.Disabled = false
+	return c.updateUser(user, models.AuditUserEnable)
+}
+
+// ResetPassword resets the user password
+// swagger:route PUT /admin/users/{userId}/password admin resetPassword
+//
+// Sets a new password of the user (admins only), all auth, refresh and personal access
+// tokens of the user are revoked, failed login attempts are reset
+//
+//     Parameters:
+//     + name: userId
+//       in: path
+//       description: User id
+//       required: true
+//       type: integer
+//       example: 2
+//     + name: password
+//       in: body
+//       description: New password
+//       required: true
+//       type: PasswordReset
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: AdminUserResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Admin) ResetPassword(id int64, reset models.PasswordReset) revel.Result {
+	user, res := c.adminGetUser(id)
+	if res != nil {
+		return res
+	}
+	reset.Validate(c.Validation)
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	user.HashedPassword, _ = bcrypt.GenerateFromPassword([]byte(reset.NewPassword), bcrypt.DefaultCost)
+	user.TokenVersion++
+	if err := auth.DefaultLimiter.Reset("user:" + user.Username); err != nil {
+		c.Log.Errorf("login limiter 'user:%s': %v", user.Username, err)
+	}
+	return c.updateUser(user, models.AuditPasswordReset)
+}
+
+// Mazes performs mazes search
+// swagger:route GET /admin/mazes admin searchAllMazes
+//
+// Search mazes of all users with the same filters, sorting and pagination as the mazes search (admins only)
+//
+//     Parameters:
+//     + name: owner
+//       in: query
+//       description: owner (creator) user id
+//       type: integer
+//       example: 2
+//     + name: limit
+//       in: query
+//       description: page size (50 by default, up to 500)
+//       type: integer
+//       example: 50
+//     + name: cursor
+//       in: query
+//       description: next page cursor from the previous page response
+//       type: string
+//     + name: sort
+//       in: query
+//       description: sort key, "-" prefix means descending order
+//       type: string
+//       example: -created
+//       enum: id,created,size,density,minPath,maxPath,-id,-created,-size,-density,-minPath,-maxPath
+//     + name: minSize
+//       in: query
+//       description: min grid size (rows x cols)
+//       type: string
+//       example: 10x10
+//     + name: maxSize
+//       in: query
+//       description: max grid size (rows x cols)
+//       type: string
+//       example: 20x20
+//     + name: minDensity
+//       in: query
+//       description: min walls density (walls / cells)
+//       type: number
+//       example: 0.2
+//     + name: maxDensity
+//       in: query
+//       description: max walls density (walls / cells)
+//       type: number
+//       example: 0.5
+//     + name: hasSolution
+//       in: query
+//       description: mazes with (true) or without (false) exact solutions, every maze is solvable but its longest path is a best found one if the solver budget was exhausted
+//       type: boolean
+//     + name: minPathFrom
+//       in: query
+//       description: min solution path length from (steps)
+//       type: integer
+//       example: 10
+//     + name: minPathTo
+//       in: query
+//       description: min solution path length to (steps)
+//       type: integer
+//       example: 60
+//     + name: createdAfter
+//       in: query
+//       description: created after time (RFC 3339)
+//       type: string
+//       example: 2022-08-01T00:00:00Z
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: AdminMazeSearchResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Admin) Mazes(owner int64) revel.Result {
+	query := c.mazeQuery()
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	where, args := "", []interface{}{}
+	if owner != 0 {
+		where, args = "OwnerID=?", []interface{}{owner}
+	}
+	mazes, next, err := c.searchMazes(query, where, args...)
+	if err != nil {
+		return c.internalError()
+	}
+
+	items := make([]*models.AdminMaze, len(mazes))
+	for i, maze := range mazes {
+		items[i] = &models.AdminMaze{Maze: maze, OwnerID: maze.OwnerID}
+	}
+	return c.RenderJSON(models.AdminMazeSearchResponse{OK: true, Items: items, NextCursor: next})
+}
+
+// DeleteMaze deletes the maze
+// swagger:route DELETE /admin/mazes/{mazeId} admin deleteAnyMaze
+//
+// Deletes a maze of any user, e.g. abusive content (admins only)
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: MazeResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Admin) DeleteMaze(id int64) revel.Result {
+	maze, err := c.getMaze(id)
+	if err != nil {
+		return c.internalError()
+	}
+	if maze == nil {
+		c.Validation.Error("Not found").Key("id")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	if err := c.deleteMaze(maze); err != nil {
+		return c.internalError()
+	}
+	return c.RenderJSON(models.MazeResponse{OK: true, ID: maze.ID})
+}
+
+// adminGetUser performs user lookup by id, returns error result if not found
+func (c Admin) adminGetUser(id int64) (*models.User, revel.Result) {
+	user, err := c.Txn.Get(models.User{}, id)
+	if err != nil {
+		c.Log.Errorf("user %d lookup: %v", id, err)
+		return nil, c.internalError()
+	}
+	if user == nil {
+		c.Validation.Error("Not found").Key("id")
+		return nil, c.validationError(c.Validation.Errors)
+	}
+	return user.(*models.User), nil
+}
+
+// updateUser updates the user, revokes the user tokens, records the admin action
+// and returns JSON response with the user data
+func (c Admin) updateUser(user *models.User, action string) revel.Result {
+	if _, err := c.Txn.Update(user); err != nil {
+		c.Log.Errorf("user %d update: %v", user.ID, err)
+		return c.internalError()
+	}
+	if err := c.revokeUserTokens(user.ID); err != nil {
+		return c.internalError()
+	}
+	admin, _ := c.Session.Get("user")
+	c.audit(admin.(*models.User), action, models.AuditTargetUser, user.ID, nil)
+
+	item := &models.AdminUser{}
+	if err := c.Txn.SelectOne(item, c.Db.SqlStatementBuilder.Select(adminUserColumns...).From("User").Where("ID=?", user.ID)); err != nil {
+		c.Log.Errorf("user %d lookup: %v", user.ID, err)
+		return c.internalError()
+	}
+	return c.RenderJSON(models.AdminUserResponse{OK: true, Item: item})
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/revel/revel"
+
+	"github.com/mkulish/mazes/app/auth"
+	"github.com/mkulish/mazes/app/models"
+)
+
+// last used timestamp precision, limits API key updates on frequent requests
+const apiKeyUsagePrecision = 60
+
+// APIKeys returns API keys of the user
+// swagger:route GET /user/keys user listAPIKeys
+//
+// Returns API keys (without the keys themselves) with last used timestamps
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: APIKeyListResponse
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) APIKeys() revel.Result {
+	user, _ := c.Session.Get("user")
+
+	keys := []*models.APIKey{}
+	_, err := c.Txn.Select(&keys, c.Db.SqlStatementBuilder.Select("*").From("APIKey").
+		Where("UserID=?", user.(*models.User).ID).OrderBy("ID"))
+	if err != nil {
+		c.Log.Errorf("API keys lookup: %v", err)
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.APIKeyListResponse{OK: true, Items: keys})
+}
+
+// CreateAPIKey issues an API key
+// swagger:route POST /user/keys user createAPIKey
+//
+// Issues a scoped API key for non-interactive clients, the key is sent
+// in the X-API-Key header or as "Authorization: ApiKey <key>".
+// The key is returned once, only its hash is stored.
+//
+//     Parameters:
+//     + name: key
+//       in: body
+//       description: Key parameters
+//       required: true
+//       type: APIKeyRequest
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: APIKeyResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) CreateAPIKey(request models.APIKeyRequest) revel.Result {
+	user, _ := c.Session.Get("user")
+
+	request.Validate(c.Validation)
+	c.Validation.Required(request.Scope).Key("scope")
+	c.checkScope(request.Scope)
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	rawKey, hash := auth.NewAPIKey()
+	key := &models.APIKey{
+		UserID: user.(*models.User).ID,
+		Hash:   hash,
+		Prefix: rawKey[:len(auth.APIKeyPrefix) + 8],
+		Label:  request.Label,
+		Scope:  auth.JoinScope(auth.ParseScope(request.Scope)),
+	}
+	if request.ExpiresIn > 0 {
+		key.ExpiresAt = time.Now().Add(time.Duration(request.ExpiresIn) * time.Second).Unix()
+	}
+	if err := c.Txn.Insert(key); err != nil {
+		c.Log.Errorf("API key insert: %v", err)
+		return c.internalError()
+	}
+	c.audit(user.(*models.User), models.AuditAPIKeyCreate, models.AuditTargetAPIKey, key.ID, models.NewAuditDiff(nil, key))
+
+	return c.RenderJSON(models.APIKeyResponse{OK: true, Key: rawKey, Item: key})
+}
+
+// UpdateAPIKey changes API key label
+// swagger:route PATCH /user/keys/{keyId} user updateAPIKey
+//
+// Changes API key label, the scope and expiration are not changed
+//
+//     Parameters:
+//     + name: keyId
+//       in: path
+//       description: API key id
+//       required: true
+//       type: integer
+//     + name: key
+//       in: body
+//       description: Key parameters
+//       required: true
+//       type: APIKeyRequest
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: APIKeyResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) UpdateAPIKey(id int64, request models.APIKeyRequest) revel.Result {
+	key, result := c.ownAPIKey(id)
+	if result != nil {
+		return result
+	}
+
+	request.Validate(c.Validation)
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	existing := *key
+	key.Label = request.Label
+	if _, err := c.Txn.Update(key); err != nil {
+		c.Log.Errorf("API key %d update: %v", id, err)
+		return c.internalError()
+	}
+	user, _ := c.Session.Get("user")
+	c.audit(user.(*models.User), models.AuditAPIKeyUpdate, models.AuditTargetAPIKey, key.ID, models.NewAuditDiff(&existing, key))
+	return c.RenderJSON(models.APIKeyResponse{OK: true, Item: key})
+}
+
+// DeleteAPIKey revokes an API key
+// swagger:route DELETE /user/keys/{keyId} user deleteAPIKey
+//
+// Revokes an API key immediately
+//
+//     Parameters:
+//     + name: keyId
+//       in: path
+//       description: API key id
+//       required: true
+//       type: integer
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: LogoutResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) DeleteAPIKey(id int64) revel.Result {
+	key, result := c.ownAPIKey(id)
+	if result != nil {
+		return result
+	}
+
+	if _, err := c.Txn.Delete(key); err != nil {
+		c.Log.Errorf("API key %d delete: %v", id, err)
+		return c.internalError()
+	}
+	user, _ := c.Session.Get("user")
+	c.audit(user.(*models.User), models.AuditAPIKeyDelete, models.AuditTargetAPIKey, key.ID, models.NewAuditDiff(key, nil))
+	return c.RenderJSON(models.LogoutResponse{OK: true})
+}
+
+// apiKeyAuth verifies API key and returns the user with the key scope, updates last used timestamp
+func (c App) apiKeyAuth(rawKey string) (*models.User, string, revel.Result) {
+	var keys []*models.APIKey
+	_, err := c.Txn.Select(&keys, c.Db.SqlStatementBuilder.Select("*").From("APIKey").
+		Where("Hash=?", auth.HashToken(rawKey)))
+	if err != nil {
+		c.Log.Errorf("API key lookup: %v", err)
+		return nil, "", c.internalError()
+	}
+
+	now := time.Now().Unix()
+	if len(keys) == 0 || (keys[0].ExpiresAt > 0 && keys[0].ExpiresAt < now) {
+		return nil, "", c.unauthorizedError()
+	}
+	key := keys[0]
+
+	user, err := c.Txn.Get(models.User{}, key.UserID)
+	if err != nil {
+		c.Log.Errorf("user %d lookup: %v", key.UserID, err)
+		return nil, "", c.internalError()
+	}
+	if user == nil {
+		return nil, "", c.unauthorizedError()
+	}
+
+	if now - key.LastUsed >= apiKeyUsagePrecision {
+		_, err := c.Txn.ExecUpdate(c.Db.SqlStatementBuilder.Update("APIKey").
+			Set("LastUsed", now - now % apiKeyUsagePrecision).Where("ID=?", key.ID))
+		if err != nil {
+			c.Log.Errorf("API key %d usage update: %v", key.ID, err)
+		}
+	}
+
+	c.Args["apiKey"] = key
+	return user.(*models.User), key.Scope, nil
+}
+
+// ownAPIKey performs API key lookup by id, ensures the key belongs to the current user
+func (c User) ownAPIKey(id int64) (*models.APIKey, revel.Result) {
+	user, _ := c.Session.Get("user")
+
+	var keys []*models.APIKey
+	_, err := c.Txn.Select(&keys, c.Db.SqlStatementBuilder.Select("*").From("APIKey").
+		Where("ID=? AND UserID=?", id, user.(*models.User).ID))
+	if err != nil {
+		c.Log.Errorf("API key %d lookup: %v", id, err)
+		return nil, c.internalError()
+	}
+	if len(keys) == 0 {
+		c.Validation.Error("Not found").Key("id")
+		return nil, c.validationError(c.Validation.Errors)
+	}
+	return keys[0], nil
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,34 +12,139 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 	gorpController "github.com/revel/modules/orm/gorp/app/controllers"
 
+	"github.com/mkulish/mazes/app/auth"
 	"github.com/mkulish/mazes/app/models"
 )
 
+// actionScopes lists OAuth2 scopes required by the actions as documented in swagger,
+// actions missing here require the write scope, empty scope allows any valid token
+var actionScopes = map[string]string{
+	"Maze.Search":         auth.ScopeRead,
+	"Maze.Public":         auth.ScopeRead,
+	"Maze.Create":         auth.ScopeWrite,
+	"Maze.Generate":       auth.ScopeWrite,
+	"Maze.Show":           auth.ScopeRead,
+	"Maze.SVG":            auth.ScopeRead,
+	"Maze.PNG":            auth.ScopeRead,
+	"Maze.Update":         auth.ScopeWrite,
+	"Maze.Patch":          auth.ScopeWrite,
+	"Maze.Delete":         auth.ScopeWrite,
+	"Maze.Solution":       auth.ScopeRead,
+	"Maze.TraceJSON":      auth.ScopeRead,
+	"Maze.TraceGIF":       auth.ScopeRead,
+	"Maze.Shares":         auth.ScopeRead,
+	"Maze.Share":          auth.ScopeWrite,
+	"Maze.Unshare":        auth.ScopeWrite,
+	"User.Logout":         "",
+	"User.Me":             auth.ScopeRead,
+	"User.ChangePassword": auth.ScopeWrite,
+	"User.DeleteAccount":  auth.ScopeWrite,
+	"User.Tokens":         auth.ScopeRead,
+	"User.CreateToken":    auth.ScopeWrite,
+	"User.DeleteToken":    auth.ScopeWrite,
+	"User.APIKeys":        auth.ScopeRead,
+	"User.CreateAPIKey":   auth.ScopeWrite,
+	"User.UpdateAPIKey":   auth.ScopeWrite,
+	"User.DeleteAPIKey":   auth.ScopeWrite,
+	"User.Audit":          auth.ScopeRead,
+	"Org.List":            auth.ScopeRead,
+	"Org.Create":          auth.ScopeWrite,
+	"Org.Delete":          auth.ScopeWrite,
+	"Org.Members":         auth.ScopeRead,
+	"Org.SetMember":       auth.ScopeWrite,
+	"Org.RemoveMember":    auth.ScopeWrite,
+	"Admin.Users":         auth.ScopeRead,
+	"Admin.DisableUser":   auth.ScopeWrite,
+	"Admin.EnableUser":    auth.ScopeWrite,
+	"Admin.ResetPassword": auth.ScopeWrite,
+	"Admin.Mazes":         auth.ScopeRead,
+	"Admin.DeleteMaze":    auth.ScopeWrite,
+	"Admin.Audit":         auth.ScopeRead,
+}
+
 // App base controller
 type App struct {
 	gorpController.Controller
 }
 
-// Auth interceptor ensures authentication and stores user in session (if any)
+// Auth interceptor ensures authentication (auth token or API key) and the action scope,
+// stores user in session (if any)
 func (c App) Auth() revel.Result {
+	var user *models.User
+	var scope string
+	var result revel.Result
+
 	authData := strings.Split(c.Request.Header.Get("Authorization"), " ")
-	if len(authData) != 2 || authData[0] != "Bearer" {
+	if apiKey := c.Request.Header.Get("X-API-Key"); apiKey != "" {
+		user, scope, result = c.apiKeyAuth(apiKey)
+	} else if len(authData) == 2 && authData[0] == "ApiKey" {
+		user, scope, result = c.apiKeyAuth(authData[1])
+	} else if len(authData) == 2 && authData[0] == "Bearer" {
+		user, scope, result = c.tokenAuth(authData[1])
+	} else {
 		return c.unauthorizedError()
 	}
+	if result != nil {
+		return result
+	}
+	if user.Disabled {
+		return c.disabledError()
+	}
+
+	required, found := actionScopes[c.Action]
+	if !found {
+		required = auth.ScopeWrite
+	}
+	if required != "" && !auth.HasScope(scope, required) {
+		return c.forbiddenError(required)
+	}
+
+	c.Session.Set("user", user)
+	c.Args["scope"] = scope
+	return nil
+}
+
+// RequireAdmin interceptor ensures the admin role of the user stored by the auth interceptor
+func (c App) RequireAdmin() revel.Result {
+	user, err := c.Session.Get("user")
+	if user == nil || err != nil {
+		// user should be injected in the auth interceptor
+		return c.internalError()
+	}
+	if user.(*models.User).Role != models.RoleAdmin {
+		return c.permissionError(models.RoleAdmin)
+	}
+	return nil
+}
 
-	claims, err := decodeToken(authData[1])
+// tokenAuth verifies JWT auth token and returns the user with the token scope, stores the token claims
+func (c App) tokenAuth(tokenString string) (*models.User, string, revel.Result) {
+	claims, err := decodeToken(tokenString)
 	username, found := claims["username"]
-	if err != nil || ! found {
-		return c.unauthorizedError()
+	jti, _ := claims["jti"].(string)
+	if err != nil || ! found || jti == "" {
+		return nil, "", c.unauthorizedError()
+	}
+
+	revoked, err := c.tokenRevoked(jti)
+	if err != nil {
+		return nil, "", c.internalError()
+	}
+	if revoked {
+		return nil, "", c.unauthorizedError()
 	}
 
 	user, err := c.getUser(username.(string))
-	if user == nil || err != nil {
-		return c.unauthorizedError()
+	id, _ := claims["id"].(float64)
+	version, _ := claims["ver"].(float64)
+	if user == nil || err != nil || user.ID != int64(id) || user.TokenVersion != int64(version) {
+		// tokens of deleted users and tokens issued before the password change are rejected
+		return nil, "", c.unauthorizedError()
 	}
 
-	c.Session.Set("user", user)
-	return nil
+	c.Args["token"] = claims
+	scope, _ := claims["scope"].(string)
+	return user, scope, nil
 }
 
 // ValidationError returns JSON error response with validation errors and HTTP 400
@@ -52,37 +159,54 @@ func (c App) unauthorizedError() revel.Result {
 	return c.RenderJSON(models.UnauthorizedError{ Error: "Unauthorized" })
 }
 
+// forbiddenError returns JSON error response with HTTP 403 for tokens missing the required scope
+func (c App) forbiddenError(scope string) revel.Result {
+	c.Response.Status = http.StatusForbidden
+	c.Response.Out.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer error=\"insufficient_scope\", scope=\"%s\"", scope))
+	return c.RenderJSON(models.ForbiddenError{ Error: "Insufficient scope", Scope: scope })
+}
+
+// tooManyRequestsError returns JSON error response with HTTP 429 and Retry-After header
+func (c App) tooManyRequestsError(wait time.Duration) revel.Result {
+	retryAfter := int64(math.Ceil(wait.Seconds()))
+	c.Response.Status = http.StatusTooManyRequests
+	c.Response.Out.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	return c.RenderJSON(models.TooManyRequestsError{ Error: "Too many attempts, retry later", RetryAfter: retryAfter })
+}
+
+// permissionError returns JSON error response with HTTP 403 for resources without the required permission
+func (c App) permissionError(permission string) revel.Result {
+	c.Response.Status = http.StatusForbidden
+	return c.RenderJSON(models.ForbiddenError{ Error: "Insufficient permission", Permission: permission })
+}
+
+// disabledError returns JSON error response with HTTP 403 for disabled users
+func (c App) disabledError() revel.Result {
+	c.Response.Status = http.StatusForbidden
+	return c.RenderJSON(models.ForbiddenError{ Error: "Account disabled" })
+}
+
 // InternalError returns JSON error response with HTTP 500
 func (c App) internalError() revel.Result {
 	c.Response.Status = http.StatusInternalServerError
 	return c.RenderJSON(models.InternalError{ Error: "Internal error" })
 }
 
-// encodeToken returns JWT auth token
-func encodeToken(user *models.User) string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// encodeToken returns JWT auth token with the scope signed with the current key
+func encodeToken(user *models.User, jti, scope string, expiresAt time.Time) string {
+	tokenString, _ := auth.DefaultKeyring.Sign(jwt.MapClaims{
 		"id": user.ID,
 		"username": user.Username,
-		"exp":   time.Now().Add(time.Duration(24) * time.Hour).Unix(),
+		"jti": jti,
+		"scope": scope,
+		"ver": user.TokenVersion,
+		"iat": time.Now().Unix(),
+		"exp": expiresAt.Unix(),
 	})
-
-	tokenString, _ := token.SignedString(hmacSecret)
 	return tokenString
 }
 
-// decodeToken parses and verifies JWT auth token
+// decodeToken parses and verifies JWT auth token with the key from the kid header
 func decodeToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return hmacSecret, nil
-	})
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok && token.Valid {
-		return claims, nil
-	}
-
-	return jwt.MapClaims{}, err
+	return auth.DefaultKeyring.Parse(tokenString)
 }
@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"github.com/revel/revel"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Audit returns audit events of the user
+// swagger:route GET /user/me/audit user listUserAudit
+//
+// Returns audit events performed by the user or targeting the user account
+// (e.g. failed logins, admin actions), newest first
+//
+//     Parameters:
+//     + name: action
+//       in: query
+//       description: action or actions prefix
+//       type: string
+//       example: maze
+//     + name: targetType
+//       in: query
+//       description: target type
+//       type: string
+//       enum: user,token,apikey,maze
+//     + name: targetId
+//       in: query
+//       description: target id
+//       type: integer
+//     + name: since
+//       in: query
+//       description: events after time (RFC 3339)
+//       type: string
+//       example: 2022-08-01T00:00:00Z
+//     + name: limit
+//       in: query
+//       description: page size (50 by default, up to 500)
+//       type: integer
+//       example: 50
+//     + name: cursor
+//       in: query
+//       description: next page cursor from the previous page response
+//       type: integer
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: AuditListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) Audit() revel.Result {
+	query := c.auditQuery()
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	user, _ := c.Session.Get("user")
+	userID := user.(*models.User).ID
+	events, next, err := c.searchAudit(query, "ActorID=? OR (TargetType=? AND TargetID=?)", userID, models.AuditTargetUser, userID)
+	if err != nil {
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.AuditListResponse{OK: true, Items: events, NextCursor: next})
+}
+
+// Audit returns audit events of all users
+// swagger:route GET /audit admin listAudit
+//
+// Returns audit events of all users, newest first (admins only)
+//
+//     Parameters:
+//     + name: actor
+//       in: query
+//       description: actor user id
+//       type: integer
+//       example: 2
+//     + name: action
+//       in: query
+//       description: action or actions prefix
+//       type: string
+//       example: user.login
+//     + name: targetType
+//       in: query
+//       description: target type
+//       type: string
+//       enum: user,token,apikey,maze
+//     + name: targetId
+//       in: query
+//       description: target id
+//       type: integer
+//     + name: since
+//       in: query
+//       description: events after time (RFC 3339)
+//       type: string
+//       example: 2022-08-01T00:00:00Z
+//     + name: limit
+//       in: query
+//       description: page size (50 by default, up to 500)
+//       type: integer
+//       example: 50
+//     + name: cursor
+//       in: query
+//       description: next page cursor from the previous page response
+//       type: integer
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: AuditListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Admin) Audit() revel.Result {
+	query := c.auditQuery()
+	c.Params.Bind(&query.ActorID, "actor")
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	events, next, err := c.searchAudit(query, "")
+	if err != nil {
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.AuditListResponse{OK: true, Items: events, NextCursor: next})
+}
+
+// audit records the event in the request transaction, failures are logged without interrupting the action.
+// Anonymous actors (failed logins) are passed without ID
+func (c App) audit(actor *models.User, action, targetType string, targetID int64, diff models.AuditDiff) {
+	event := &models.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         c.ClientIP,
+		Diff:       diff,
+	}
+	if actor != nil {
+		event.ActorID, event.Actor = actor.ID, actor.Username
+	}
+
+	if err := c.Txn.Insert(event); err != nil {
+		c.Log.Errorf("audit event '%s' insert: %v", action, err)
+	}
+}
+
+// auditQuery binds audit events query params
+func (c App) auditQuery() models.AuditQuery {
+	var query models.AuditQuery
+	c.Params.Bind(&query.Limit, "limit")
+	c.Params.Bind(&query.Cursor, "cursor")
+	c.Params.Bind(&query.Action, "action")
+	c.Params.Bind(&query.TargetType, "targetType")
+	c.Params.Bind(&query.TargetID, "targetId")
+	c.Params.Bind(&query.Since, "since")
+
+	query.Validate(c.Validation)
+	return query
+}
+
+// searchAudit performs audit events search within the condition (all events if empty),
+// returns the page and the next page cursor
+func (c App) searchAudit(q models.AuditQuery, where string, args ...interface{}) ([]*models.AuditEvent, int64, error) {
+	query := c.Db.SqlStatementBuilder.Select("*").From("AuditEvent")
+	if where != "" {
+		query = query.Where("(" + where + ")", args...)
+	}
+
+	// filters
+	if q.ActorID != 0 {
+		query = query.Where("ActorID=?", q.ActorID)
+	}
+	if q.Action != "" {
+		query = query.Where("(Action=? OR Action LIKE ?)", q.Action, q.Action + ".%")
+	}
+	if q.TargetType != "" {
+		query = query.Where("TargetType=?", q.TargetType)
+	}
+	if q.TargetID != 0 {
+		query = query.Where("TargetID=?", q.TargetID)
+	}
+	if !q.SinceTime.IsZero() {
+		query = query.Where("Time>?", q.SinceTime.Unix())
+	}
+	if q.Cursor != 0 {
+		query = query.Where("ID<?", q.Cursor)
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = models.DefaultSearchLimit
+	}
+
+	events := []*models.AuditEvent{}
+	if _, err := c.Txn.Select(&events, query.OrderBy("ID DESC").Limit(uint64(limit + 1))); err != nil {
+		c.Log.Errorf("audit search: %v", err)
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(events) > limit {
+		events = events[:limit]
+		next = events[limit - 1].ID
+	}
+	return events, next, nil
+}
@@ -1,13 +1,21 @@
 package controllers
 
 import (
+	"bytes"
 	"database/sql"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 	"github.com/revel/revel"
 
+	"github.com/mkulish/mazes/app/generator"
 	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/render"
 	"github.com/mkulish/mazes/app/services"
 )
 
@@ -19,37 +27,657 @@ type Maze struct {
 // Search performs mazes search
 // swagger:route GET /maze maze searchMazes
 //
-// Search mazes
+// Search own mazes and mazes of the user organizations (or mazes shared with the user)
+// with filters, sorting and cursor-based pagination
+//
+//     Parameters:
+//     + name: limit
+//       in: query
+//       description: page size (50 by default, up to 500)
+//       type: integer
+//       example: 50
+//     + name: cursor
+//       in: query
+//       description: next page cursor from the previous page response
+//       type: string
+//     + name: sort
+//       in: query
+//       description: sort key, "-" prefix means descending order
+//       type: string
+//       example: -created
+//       enum: id,created,size,density,minPath,maxPath,-id,-created,-size,-density,-minPath,-maxPath
+//     + name: minSize
+//       in: query
+//       description: min grid size (rows x cols)
+//       type: string
+//       example: 10x10
+//     + name: maxSize
+//       in: query
+//       description: max grid size (rows x cols)
+//       type: string
+//       example: 20x20
+//     + name: minDensity
+//       in: query
+//       description: min walls density (walls / cells)
+//       type: number
+//       example: 0.2
+//     + name: maxDensity
+//       in: query
+//       description: max walls density (walls / cells)
+//       type: number
+//       example: 0.5
+//     + name: hasSolution
+//       in: query
+//       description: mazes with (true) or without (false) exact solutions, every maze is solvable but its longest path is a best found one if the solver budget was exhausted
+//       type: boolean
+//     + name: minPathFrom
+//       in: query
+//       description: min solution path length from (steps)
+//       type: integer
+//       example: 10
+//     + name: minPathTo
+//       in: query
+//       description: min solution path length to (steps)
+//       type: integer
+//       example: 60
+//     + name: createdAfter
+//       in: query
+//       description: created after time (RFC 3339)
+//       type: string
+//       example: 2022-08-01T00:00:00Z
+//     + name: shared
+//       in: query
+//       description: mazes shared with the user instead of own mazes
+//       type: boolean
+//     + name: owner
+//       in: query
+//       description: owner filter, "me" for personal mazes or organization id for the org mazes
+//       type: string
+//       example: me
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: MazeSearchResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Search(shared bool, owner string) revel.Result {
+	query := c.mazeQuery()
+	if shared && owner != "" {
+		c.Validation.Error("Not allowed with shared mazes").Key("owner")
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	user, _ := c.Session.Get("user")
+	userID := user.(*models.User).ID
+	where, args := "(OrgID=0 AND OwnerID=?) OR OrgID IN (SELECT OrgID FROM OrgMember WHERE UserID=?)", []interface{}{userID, userID}
+	if shared {
+		where = "Visibility<>? AND ID IN (SELECT MazeID FROM MazeShare WHERE UserID=?)"
+		args = []interface{}{models.VisibilityPrivate, userID}
+	} else if owner == "me" {
+		where, args = "OrgID=0 AND OwnerID=?", []interface{}{userID}
+	} else if owner != "" {
+		orgID, _ := strconv.ParseInt(owner, 10, 64)
+		role, err := c.orgRole(orgID, userID)
+		if err != nil {
+			return c.internalError()
+		}
+		if role == "" {
+			c.Validation.Error("Should be me or organization id").Key("owner")
+			return c.validationError(c.Validation.Errors)
+		}
+		where, args = "OrgID=?", []interface{}{orgID}
+	}
+	mazes, next, err := c.searchMazes(query, where, args...)
+	if err != nil {
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.MazeSearchResponse{OK: true, Items: mazes, NextCursor: next})
+}
+
+// Public performs public mazes search
+// swagger:route GET /maze/public maze searchPublicMazes
+//
+// Search public mazes of all users with the same filters, sorting and pagination as the mazes search
+//
+//     Parameters:
+//     + name: limit
+//       in: query
+//       description: page size (50 by default, up to 500)
+//       type: integer
+//       example: 50
+//     + name: cursor
+//       in: query
+//       description: next page cursor from the previous page response
+//       type: string
+//     + name: sort
+//       in: query
+//       description: sort key, "-" prefix means descending order
+//       type: string
+//       example: -created
+//       enum: id,created,size,density,minPath,maxPath,-id,-created,-size,-density,-minPath,-maxPath
+//     + name: minSize
+//       in: query
+//       description: min grid size (rows x cols)
+//       type: string
+//       example: 10x10
+//     + name: maxSize
+//       in: query
+//       description: max grid size (rows x cols)
+//       type: string
+//       example: 20x20
+//     + name: minDensity
+//       in: query
+//       description: min walls density (walls / cells)
+//       type: number
+//       example: 0.2
+//     + name: maxDensity
+//       in: query
+//       description: max walls density (walls / cells)
+//       type: number
+//       example: 0.5
+//     + name: hasSolution
+//       in: query
+//       description: mazes with (true) or without (false) exact solutions, every maze is solvable but its longest path is a best found one if the solver budget was exhausted
+//       type: boolean
+//     + name: minPathFrom
+//       in: query
+//       description: min solution path length from (steps)
+//       type: integer
+//       example: 10
+//     + name: minPathTo
+//       in: query
+//       description: min solution path length to (steps)
+//       type: integer
+//       example: 60
+//     + name: createdAfter
+//       in: query
+//       description: created after time (RFC 3339)
+//       type: string
+//       example: 2022-08-01T00:00:00Z
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: MazeSearchResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Public() revel.Result {
+	query := c.mazeQuery()
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	mazes, next, err := c.searchMazes(query, "Visibility=?", models.VisibilityPublic)
+	if err != nil {
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.MazeSearchResponse{OK: true, Items: mazes, NextCursor: next})
+}
+
+// mazeQuery binds and validates mazes search params
+func (c App) mazeQuery() models.MazeQuery {
+	var query models.MazeQuery
+	c.Params.Bind(&query.Limit, "limit")
+	c.Params.Bind(&query.Cursor, "cursor")
+	c.Params.Bind(&query.Sort, "sort")
+	c.Params.Bind(&query.MinSize, "minSize")
+	c.Params.Bind(&query.MaxSize, "maxSize")
+	c.Params.Bind(&query.MinDensity, "minDensity")
+	c.Params.Bind(&query.MaxDensity, "maxDensity")
+	c.Params.Bind(&query.HasSolution, "hasSolution")
+	c.Params.Bind(&query.MinPathFrom, "minPathFrom")
+	c.Params.Bind(&query.MinPathTo, "minPathTo")
+	c.Params.Bind(&query.CreatedAfter, "createdAfter")
+
+	query.Validate(c.Validation)
+	return query
+}
+
+// Create performs maze validation, processing and insert
+// swagger:route POST /maze maze createMaze
+//
+// Creates a maze, performs validation and path processing.
+// The grid could be sent as ASCII text: # - wall, . - open cell, S - entrance, E - explicit exit (optional).
+//
+//     Consumes:
+//     - application/json
+//     - text/plain
+//
+//     Parameters:
+//     + name: maze
+//       in: body
+//       description: Maze data (or ASCII grid)
+//       required: true
+//       type: Maze
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: MazeResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Create(maze models.Maze) revel.Result {	
+	user, err := c.Session.Get("user")
+
+	if c.Request.ContentType == "text/plain" {
+		// ASCII grid body is not parsed by revel
+		body, _ := ioutil.ReadAll(c.Request.GetBody())
+		parsed, err := services.ParseASCII(string(body))
+		if err != nil {
+			c.Validation.Error("Incorrect ASCII grid: %v", err).Key("maze")
+			return c.validationError(c.Validation.Errors)
+		}
+		maze = *parsed
+	}
+	maze.OwnerID = user.(*models.User).ID
+	if res := c.authorizeOrgMaze(maze.OrgID); res != nil {
+		return res
+	}
+
+	if ! c.processMaze(&maze) {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	err = c.Txn.Insert(&maze)
+	if err != nil {
+		c.Log.Errorf("maze '%v' insert: %v", maze, err)
+		return c.internalError()
+	}
+	c.audit(user.(*models.User), models.AuditMazeCreate, models.AuditTargetMaze, maze.ID, models.NewAuditDiff(nil, &maze))
+
+	return c.RenderJSON(models.MazeResponse{OK: true, ID: maze.ID})
+}
+
+// Generate generates a maze with the algorithm, performs path processing and insert
+// swagger:route POST /maze/generate maze generateMaze
+//
+// Generates a perfect maze with a single exit on the bottom row, performs path processing.
+// With constraints mazes are regenerated with the next seeds and mutated (braided) until
+// the measured metrics are within the ranges or the attempts budget is exhausted.
+//
+//     Parameters:
+//     + name: generation
+//       in: body
+//       description: Maze generation parameters
+//       required: true
+//       type: MazeGeneration
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: MazeGenerationResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Generate(generation models.MazeGeneration) revel.Result {
+	user, _ := c.Session.Get("user")
+
+	generation.Validate(c.Validation)
+	if generation.Algorithm == "" {
+		generation.Algorithm = generator.DefaultAlgorithm
+	}
+	if generator.Find(generation.Algorithm) == nil {
+		c.Validation.Error("Should be one of: %s", strings.Join(generator.Algorithms(), ", ")).Key("algorithm")
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+	if res := c.authorizeOrgMaze(generation.OrgID); res != nil {
+		return res
+	}
+
+	if generation.Seed == 0 {
+		// 53 bits keep the seed exact in JSON numbers
+		generation.Seed = time.Now().UnixNano() & (1 << 53 - 1)
+	}
+
+	maze := models.Maze{
+		OwnerID: user.(*models.User).ID,
+		OrgID: generation.OrgID,
+		Entrance: generation.Entrance,
+		GridSize: generation.GridSize,
+	}
+	if err := generator.Generate(&maze, generation.Algorithm, generation.Seed); err != nil {
+		c.Validation.Error(err.Error()).Key("entrance")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	var result *generator.Result
+	if generation.Constraints != nil {
+		budget := generator.DefaultBudget
+		if generation.Attempts > 0 && (budget.Attempts == 0 || generation.Attempts < budget.Attempts) {
+			budget.Attempts = generation.Attempts
+		}
+
+		res, err := generator.GenerateConstrained(&maze, generation.Algorithm, generation.Seed, *generation.Constraints, budget)
+		if err != nil {
+			c.Validation.Error(err.Error()).Key("constraints")
+			return c.validationError(c.Validation.Errors)
+		}
+		result = &res
+	}
+
+	if ! c.processMaze(&maze) {
+		return c.validationError(c.Validation.Errors)
+	}
+	if result != nil {
+		// stored solutions are searched by the default solvers, the checked metrics should match them
+		minPath, maxPath := strings.Count(maze.MinPathStr, ","), strings.Count(maze.MaxPathStr, ",")
+		if !result.MaxPathExact {
+			// not measured by the generator
+			result.Metrics.MaxPath = maxPath
+		}
+		if minPath != result.Metrics.MinPath || maxPath != result.Metrics.MaxPath {
+			c.Validation.Error("Constraints not verified: solver paths are %d and %d steps, measured %d and %d",
+				minPath, maxPath, result.Metrics.MinPath, result.Metrics.MaxPath).Key("constraints")
+			return c.validationError(c.Validation.Errors)
+		}
+	}
+
+	if err := c.Txn.Insert(&maze); err != nil {
+		c.Log.Errorf("maze '%v' insert: %v", maze, err)
+		return c.internalError()
+	}
+	c.audit(user.(*models.User), models.AuditMazeCreate, models.AuditTargetMaze, maze.ID, models.NewAuditDiff(nil, &maze))
+
+	resp := models.MazeGenerationResponse{
+		OK: true,
+		ID: maze.ID,
+		Seed: generation.Seed,
+		Algorithm: generation.Algorithm,
+	}
+	if result != nil {
+		resp.Seed, resp.Metrics, resp.Attempts = result.Seed, &result.Metrics, result.Attempts
+	}
+	return c.RenderJSON(resp)
+}
+
+// Show returns maze data
+// swagger:route GET /maze/{mazeId} maze getMaze
+//
+// Get maze, the grid is drawn as ASCII text for text/plain Accept header
+// (# - wall, . - open cell, S - entrance, E - explicit exit, * - solution path)
+//
+//     Produces:
+//     - application/json
+//     - text/plain
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: path
+//       in: query
+//       description: draw _min_ or _max_ steps solution path on the ASCII grid
+//       required: false
+//       type: string
+//       example: min
+//       pattern: ^min|max$
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: MazeItemResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Show(id int64, path string) revel.Result {
+	maze, res := c.authorizeMaze(id, models.PermissionRead)
+	if res != nil {
+		return res
+	}
+
+	if c.Request.Format != "txt" {
+		return c.RenderJSON(models.MazeItemResponse{OK: true, Item: maze})
+	}
+
+	overlay := c.overlayPath(maze, path)
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	return c.RenderText(services.RenderASCII(maze, overlay))
+}
+
+// SVG draws the maze as SVG image
+// swagger:route GET /maze/{mazeId}/render.svg maze renderMazeSVG
+//
+// Draws the maze grid, walls, entrance, detected exit and optional solution path as SVG image
+//
+//     Produces:
+//     - image/svg+xml
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: path
+//       in: query
+//       description: draw _min_ or _max_ steps solution path
+//       type: string
+//       example: min
+//       pattern: ^min|max$
+//     + name: cellSize
+//       in: query
+//       description: cell size in pixels (4..64, 20 by default)
+//       type: integer
+//       example: 20
+//     + name: theme
+//       in: query
+//       description: colour theme
+//       type: string
+//       enum: light,dark
+//     + name: backgroundColour
+//       in: query
+//       description: background colour override (hex RRGGBB)
+//       type: string
+//       example: ffffff
+//     + name: wallColour
+//       in: query
+//       description: wall colour override (hex RRGGBB)
+//       type: string
+//       example: 333333
+//     + name: pathColour
+//       in: query
+//       description: path colour override (hex RRGGBB)
+//       type: string
+//       example: 2196f3
+//     + name: labels
+//       in: query
+//       description: draw column letters and row numbers
+//       type: boolean
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: description: SVG image
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) SVG(id int64) revel.Result {
+	maze, res := c.authorizeMaze(id, models.PermissionRead)
+	if res != nil {
+		return res
+	}
+
+	path, opts := c.renderOptions(maze)
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	image := render.SVG(maze, path, opts)
+	return c.RenderBinary(bytes.NewReader(image), fmt.Sprintf("maze-%d.svg", maze.ID), revel.Inline, time.Unix(maze.Created, 0))
+}
+
+// PNG draws the maze as PNG image
+// swagger:route GET /maze/{mazeId}/render.png maze renderMazePNG
+//
+// Draws the maze grid, walls, entrance, detected exit and optional solution path as PNG image
+//
+//     Produces:
+//     - image/png
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: path
+//       in: query
+//       description: draw _min_ or _max_ steps solution path
+//       type: string
+//       example: min
+//       pattern: ^min|max$
+//     + name: cellSize
+//       in: query
+//       description: cell size in pixels (4..64, 20 by default)
+//       type: integer
+//       example: 20
+//     + name: theme
+//       in: query
+//       description: colour theme
+//       type: string
+//       enum: light,dark
+//     + name: backgroundColour
+//       in: query
+//       description: background colour override (hex RRGGBB)
+//       type: string
+//       example: ffffff
+//     + name: wallColour
+//       in: query
+//       description: wall colour override (hex RRGGBB)
+//       type: string
+//       example: 333333
+//     + name: pathColour
+//       in: query
+//       description: path colour override (hex RRGGBB)
+//       type: string
+//       example: 2196f3
+//     + name: labels
+//       in: query
+//       description: draw column letters and row numbers
+//       type: boolean
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: description: PNG image
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) PNG(id int64) revel.Result {
+	maze, res := c.authorizeMaze(id, models.PermissionRead)
+	if res != nil {
+		return res
+	}
+
+	path, opts := c.renderOptions(maze)
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	image, err := render.PNG(maze, path, opts)
+	if err != nil {
+		c.Log.Errorf("maze '%d' render: %v", maze.ID, err)
+		return c.internalError()
+	}
+	return c.RenderBinary(bytes.NewReader(image), fmt.Sprintf("maze-%d.png", maze.ID), revel.Inline, time.Unix(maze.Created, 0))
+}
+
+// Update replaces maze data, performs validation and path processing
+// swagger:route PUT /maze/{mazeId} maze updateMaze
+//
+// Updates a maze, performs validation and path processing
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: maze
+//       in: body
+//       description: Maze data
+//       required: true
+//       type: Maze
 //
 //     Security:
-//       oauth2: read
+//       oauth2: write
 //
 //     Responses:
-//       200: MazeSearchResponse
+//       200: MazeResponse
 //       400: ValidationError
 //       401: UnauthorizedError
+//       403: ForbiddenError
 //       500: InternalError
-func (c Maze) Search() revel.Result {
-	user, _ := c.Session.Get("user")
-	mazes, err := c.searchMazes(user.(*models.User).ID)
-	if err != nil {
-		return c.internalError()
+func (c Maze) Update(id int64, maze models.Maze) revel.Result {
+	existing, res := c.authorizeMaze(id, models.PermissionEdit)
+	if res != nil {
+		return res
 	}
 
-	return c.RenderJSON(models.MazeSearchResponse{OK: true, Items: mazes})
+	maze.ID, maze.OwnerID, maze.OrgID, maze.Created = existing.ID, existing.OwnerID, existing.OrgID, existing.Created
+	if maze.Visibility == "" {
+		maze.Visibility = existing.Visibility
+	}
+	if res := c.authorizeVisibility(existing, maze.Visibility); res != nil {
+		return res
+	}
+	return c.updateMaze(existing, &maze)
 }
 
-// Create performs maze validation, processing and insert
-// swagger:route POST /maze maze createMaze
+// Patch applies JSON Merge Patch to the maze, performs validation and path processing
+// swagger:route PATCH /maze/{mazeId} maze patchMaze
 //
-// Creates a maze, performs validation and path processing
+// Patches a maze (JSON Merge Patch with walls add/remove), performs validation and path processing
+//
+//     Consumes:
+//     - application/merge-patch+json
+//     - application/json
 //
 //     Parameters:
-//     + name: maze
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: patch
 //       in: body
-//       description: Maze data
+//       description: Maze merge patch
 //       required: true
-//       type: Maze
+//       type: MazePatch
 //
 //     Security:
 //       oauth2: write
@@ -58,40 +686,62 @@ func (c Maze) Search() revel.Result {
 //       200: MazeResponse
 //       400: ValidationError
 //       401: UnauthorizedError
+//       403: ForbiddenError
 //       500: InternalError
-func (c Maze) Create(maze models.Maze) revel.Result {	
-	user, err := c.Session.Get("user")
-	maze.OwnerID = user.(*models.User).ID
-
-	maze.Validate(c.Validation)
-	if ! c.Validation.HasErrors() {
-		services.ValidateMazeGrid(&maze, c.Validation)
+func (c Maze) Patch(id int64) revel.Result {
+	maze, res := c.authorizeMaze(id, models.PermissionEdit)
+	if res != nil {
+		return res
 	}
-	if ! c.Validation.HasErrors() {
-		g := new(errgroup.Group)
-		var minPath, maxPath []string
-		g.Go(func() error {
-			minPath, err = services.SolveMaze(&maze, true)
-			return err
-		})
-		g.Go(func() error {
-			maxPath, err = services.SolveMaze(&maze, false)
-			return err
-		})
-		if err := g.Wait(); err != nil {
-			c.Validation.Error(err.Error()).Key("walls")
-		}
+	existing := *maze
 
-		maze.MinPathStr, maze.MaxPathStr = strings.Join(minPath, ","), strings.Join(maxPath, ",")
+	// merge patch content type body is not parsed by revel
+	patch := c.Params.JSON
+	if patch == nil {
+		patch, _ = ioutil.ReadAll(c.Request.GetBody())
 	}
-
-	if c.Validation.HasErrors() {
+	if err := services.PatchMaze(maze, patch); err != nil {
+		c.Validation.Error("Incorrect merge patch: %v", err).Key("patch")
 		return c.validationError(c.Validation.Errors)
 	}
+	// the org is set on creation only
+	maze.OrgID = existing.OrgID
+	if res := c.authorizeVisibility(&existing, maze.Visibility); res != nil {
+		return res
+	}
 
-	err = c.Txn.Insert(&maze)
-	if err != nil {
-		c.Log.Errorf("maze '%v' insert: %v", maze, err)
+	return c.updateMaze(&existing, maze)
+}
+
+// Delete deletes the maze
+// swagger:route DELETE /maze/{mazeId} maze deleteMaze
+//
+// Deletes a maze (owner or org owners only)
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: MazeResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Delete(id int64) revel.Result {
+	maze, res := c.authorizeMaze(id, permissionOwner)
+	if res != nil {
+		return res
+	}
+
+	if err := c.deleteMaze(maze); err != nil {
 		return c.internalError()
 	}
 
@@ -117,6 +767,13 @@ func (c Maze) Create(maze models.Maze) revel.Result {
 //       type: string
 //       example: min
 //       pattern: ^min|max$
+//     + name: algorithm
+//       in: query
+//       description: solve the maze on request with the algorithm instead of returning precalculated solution
+//       required: false
+//       type: string
+//       example: bidirectional
+//       enum: astar,bfs,bidirectional,dfs,dijkstra,idastar,longest
 //
 //     Security:
 //       oauth2: read
@@ -125,45 +782,351 @@ func (c Maze) Create(maze models.Maze) revel.Result {
 //       200: MazeSolutionResponse
 //       400: ValidationError
 //       401: UnauthorizedError
+//       403: ForbiddenError
 //       500: InternalError
-func (c Maze) Solution(id int64, steps string) revel.Result {
-	user, err := c.Session.Get("user")
-	if user == nil || err != nil {
-		// user should be injected in the auth interceptor
-		return c.internalError()
+func (c Maze) Solution(id int64, steps, algorithm string) revel.Result {
+	maze, res := c.authorizeMaze(id, models.PermissionRead)
+	if res != nil {
+		return res
+	}
+
+	var path []string
+	optimal := true
+	switch steps {
+		case services.MinSteps: path = strings.Split(maze.MinPathStr, ",")
+		case services.MaxSteps: path, optimal = strings.Split(maze.MaxPathStr, ","), maze.MaxPathOptimal
+		default: c.Validation.Error("Should be one of: min, max").Key("steps")
+	}
+
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
 	}
 
-	if id == 0 {
-		c.Validation.Error("Missing or incorrect maze id").Key("id")
+	if algorithm == "" {
+		return c.RenderJSON(models.MazeSolutionResponse{OK: true, Path: path, Optimal: optimal})
+	}
+
+	// solve on request to compare algorithms
+	solver := services.FindSolver(steps, algorithm)
+	if solver == nil {
+		c.Validation.Error("Should be one of: %s", strings.Join(services.Algorithms(steps), ", ")).Key("algorithm")
 		return c.validationError(c.Validation.Errors)
 	}
 
-	maze, err := c.getMaze(id)
+	started := time.Now()
+	path, optimal, err := solver.Solve(maze)
+	elapsed := time.Since(started)
 	if err != nil {
-		return c.internalError()
+		c.Validation.Error(err.Error()).Key("algorithm")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	return c.RenderJSON(models.MazeSolutionResponse{
+		OK: true,
+		Path: path,
+		Optimal: optimal,
+		Algorithm: algorithm,
+		Elapsed: float64(elapsed) / float64(time.Millisecond),
+	})
+}
+
+// TraceJSON returns solver search events
+// swagger:route GET /maze/{mazeId}/trace.json maze traceMazeJSON
+//
+// Solves the maze with the default min or max steps algorithm recording the search order
+// (heap pushes and pops for min steps, depth-first steps forward and back for max steps)
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: steps
+//       in: query
+//       description: trace _min_ or _max_ steps solver
+//       required: true
+//       type: string
+//       example: min
+//       pattern: ^min|max$
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: MazeTraceResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) TraceJSON(id int64, steps string) revel.Result {
+	maze, res := c.authorizeMaze(id, models.PermissionRead)
+	if res != nil {
+		return res
 	}
-	if maze == nil {
-		c.Validation.Error("Not found").Key("id")
+
+	path, algorithm, recorder := c.traceMaze(maze, steps)
+	if c.Validation.HasErrors() {
 		return c.validationError(c.Validation.Errors)
-	} else if maze.OwnerID != user.(*models.User).ID {
-		return c.unauthorizedError()
 	}
 
-	var path []string
-	switch steps {
-		case "min": path = strings.Split(maze.MinPathStr, ",")
-		case "max": path = strings.Split(maze.MaxPathStr, ",")
-		default: c.Validation.Error("Should be one of: min, max").Key("steps")
+	return c.RenderJSON(models.MazeTraceResponse{
+		OK: true,
+		Algorithm: algorithm,
+		Path: path,
+		Frames: recorder.Events,
+		Truncated: recorder.Truncated,
+	})
+}
+
+// TraceGIF animates solver search
+// swagger:route GET /maze/{mazeId}/trace.gif maze traceMazeGIF
+//
+// Solves the maze with the default min or max steps algorithm animating the search order,
+// frontier cells are drawn in the frontier colour, visited cells in the visited colour
+//
+//     Produces:
+//     - image/gif
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: steps
+//       in: query
+//       description: trace _min_ or _max_ steps solver
+//       required: true
+//       type: string
+//       example: min
+//       pattern: ^min|max$
+//     + name: frames
+//       in: query
+//       description: max animation frames, search events are grouped into frames (1..500, 100 by default)
+//       type: integer
+//       example: 100
+//     + name: cellSize
+//       in: query
+//       description: cell size in pixels (4..64, 20 by default)
+//       type: integer
+//       example: 20
+//     + name: theme
+//       in: query
+//       description: colour theme
+//       type: string
+//       enum: light,dark
+//     + name: backgroundColour
+//       in: query
+//       description: background colour override (hex RRGGBB)
+//       type: string
+//       example: ffffff
+//     + name: wallColour
+//       in: query
+//       description: wall colour override (hex RRGGBB)
+//       type: string
+//       example: 333333
+//     + name: pathColour
+//       in: query
+//       description: path colour override (hex RRGGBB)
+//       type: string
+//       example: 2196f3
+//     + name: labels
+//       in: query
+//       description: draw column letters and row numbers
+//       type: boolean
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: description: GIF image
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) TraceGIF(id int64, steps string, frames int) revel.Result {
+	maze, res := c.authorizeMaze(id, models.PermissionRead)
+	if res != nil {
+		return res
 	}
 
+	if frames == 0 {
+		frames = render.DefaultTraceFrames
+	}
+	c.Validation.Range(frames, 1, render.MaxTraceFrames).Key("frames")
+	_, opts := c.renderOptions(maze)
+	path, _, recorder := c.traceMaze(maze, steps)
 	if c.Validation.HasErrors() {
 		return c.validationError(c.Validation.Errors)
 	}
 
-	return c.RenderJSON(models.MazeSolutionResponse{OK: true, Path: path})
+	image, err := render.TraceGIF(maze, recorder.Events, path, opts, frames)
+	if err != nil {
+		c.Log.Errorf("maze '%d' trace render: %v", maze.ID, err)
+		return c.internalError()
+	}
+	return c.RenderBinary(bytes.NewReader(image), fmt.Sprintf("maze-%d-%s.gif", maze.ID, steps), revel.Inline, time.Now())
+}
+
+// traceMaze solves the maze recording the search events, returns the solution path and the algorithm
+func (c Maze) traceMaze(maze *models.Maze, steps string) ([]string, string, *services.TraceRecorder) {
+	recorder := &services.TraceRecorder{Limit: services.DefaultTraceLimit}
+	var algorithm string
+	switch steps {
+		case services.MinSteps: algorithm = services.DefaultMinAlgorithm
+		case services.MaxSteps: algorithm = services.DefaultMaxAlgorithm
+		default:
+			c.Validation.Error("Should be one of: min, max").Key("steps")
+			return nil, "", recorder
+	}
+
+	path, _, err := services.TraceMaze(maze, steps == services.MinSteps, recorder)
+	if err != nil {
+		c.Validation.Error(err.Error()).Key("steps")
+	}
+	return path, algorithm, recorder
+}
+
+// overlayPath returns precalculated solution path by steps goal to draw over the maze grid
+func (c Maze) overlayPath(maze *models.Maze, steps string) []string {
+	switch steps {
+		case "": return nil
+		case services.MinSteps: return strings.Split(maze.MinPathStr, ",")
+		case services.MaxSteps: return strings.Split(maze.MaxPathStr, ",")
+		default: c.Validation.Error("Should be one of: min, max").Key("path")
+	}
+	return nil
+}
+
+// renderOptions binds and validates maze rendering query params,
+// returns solution path overlay and rendering options
+func (c Maze) renderOptions(maze *models.Maze) ([]string, render.Options) {
+	var steps, theme string
+	opts := render.DefaultOptions()
+	c.Params.Bind(&steps, "path")
+	c.Params.Bind(&opts.CellSize, "cellSize")
+	c.Params.Bind(&theme, "theme")
+	c.Params.Bind(&opts.Labels, "labels")
+
+	path := c.overlayPath(maze, steps)
+	if opts.CellSize == 0 {
+		opts.CellSize = render.DefaultCellSize
+	}
+	c.Validation.Range(opts.CellSize, render.MinCellSize, render.MaxCellSize).Key("cellSize")
+
+	if theme != "" {
+		if _, found := render.Themes[theme]; !found {
+			c.Validation.Error("Should be one of: light, dark").Key("theme")
+		}
+		opts.Theme = render.Themes[theme]
+	}
+
+	colours := []struct {
+		key    string
+		colour *color.RGBA
+	}{
+		{"backgroundColour", &opts.Theme.Background},
+		{"wallColour", &opts.Theme.Wall},
+		{"pathColour", &opts.Theme.Path},
+	}
+	for _, param := range colours {
+		if value := c.Params.Get(param.key); value != "" {
+			colour, err := render.ParseColour(value)
+			if err != nil {
+				c.Validation.Error("Should be hex RGB colour (RRGGBB)").Key(param.key)
+			}
+			*param.colour = colour
+		}
+	}
+	return path, opts
+}
+
+// processMaze performs maze validation and precalculates solutions,
+// returns false if there are validation errors
+func (c Maze) processMaze(maze *models.Maze) bool {
+	maze.Validate(c.Validation)
+	if ! c.Validation.HasErrors() {
+		services.ValidateMazeGrid(maze, c.Validation)
+	}
+	if ! c.Validation.HasErrors() {
+		g := new(errgroup.Group)
+		var minPath, maxPath []string
+		g.Go(func() (err error) {
+			minPath, _, err = services.SolveMaze(maze, true)
+			return err
+		})
+		g.Go(func() (err error) {
+			maxPath, maze.MaxPathOptimal, err = services.SolveMaze(maze, false)
+			return err
+		})
+		if err := g.Wait(); err != nil {
+			c.Validation.Error(err.Error()).Key("walls")
+		}
+
+		maze.MinPathStr, maze.MaxPathStr = strings.Join(minPath, ","), strings.Join(maxPath, ",")
+	}
+
+	return ! c.Validation.HasErrors()
+}
+
+// updateMaze processes and stores changed maze
+func (c Maze) updateMaze(existing, maze *models.Maze) revel.Result {
+	if ! c.processMaze(maze) {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	if _, err := c.Txn.Update(maze); err != nil {
+		c.Log.Errorf("maze '%v' update: %v", maze, err)
+		return c.internalError()
+	}
+	user, _ := c.Session.Get("user")
+	c.audit(user.(*models.User), models.AuditMazeUpdate, models.AuditTargetMaze, maze.ID, models.NewAuditDiff(existing, maze))
+
+	return c.RenderJSON(models.MazeResponse{OK: true, ID: maze.ID})
+}
+
+// authorizeOrgMaze checks that the user could create mazes in the organization (editors and owners),
+// personal mazes are not checked
+func (c Maze) authorizeOrgMaze(orgID int64) revel.Result {
+	if orgID == 0 {
+		return nil
+	}
+
+	user, _ := c.Session.Get("user")
+	role, err := c.orgRole(orgID, user.(*models.User).ID)
+	if err != nil {
+		return c.internalError()
+	}
+	if role == "" {
+		c.Validation.Error("Not found").Key("orgId")
+		return c.validationError(c.Validation.Errors)
+	}
+	if orgRoles[role] < orgRoles[models.OrgRoleEditor] {
+		return c.permissionError(models.OrgRoleEditor)
+	}
+	return nil
+}
+
+// deleteMaze deletes the maze with its share list, records the deletion by the current user
+func (c App) deleteMaze(maze *models.Maze) error {
+	_, err := c.Txn.GetMap().Exec("DELETE FROM MazeShare WHERE MazeID = ?", maze.ID)
+	if err == nil {
+		_, err = c.Txn.Delete(maze)
+	}
+	if err != nil {
+		c.Log.Errorf("maze '%d' delete: %v", maze.ID, err)
+		return err
+	}
+
+	user, _ := c.Session.Get("user")
+	c.audit(user.(*models.User), models.AuditMazeDelete, models.AuditTargetMaze, maze.ID, models.NewAuditDiff(maze, nil))
+	return nil
 }
 
-// getUser performs maze lookup by id
+// getMaze performs maze lookup by id
 func (c App) getMaze(id int64) (*models.Maze, error) {
 	maze := &models.Maze{}
 	err := c.Txn.SelectOne(maze, c.Db.SqlStatementBuilder.Select("*").From("Maze").Where("ID=?", id))
@@ -178,22 +1141,95 @@ func (c App) getMaze(id int64) (*models.Maze, error) {
 	return maze, err
 }
 
-// searchMazes performs mazes search
-func (c Maze) searchMazes(ownerID int64) ([]*models.Maze, error) {
+// mazeSortKeys maps search sort keys to SQL expressions and item values for cursors
+var mazeSortKeys = map[string]struct {
+	expr  string
+	value func(m *models.Maze) float64
+}{
+	"id":      {"ID", func(m *models.Maze) float64 { return float64(m.ID) }},
+	"created": {"Created", func(m *models.Maze) float64 { return float64(m.Created) }},
+	"size":    {"(Rows * Cols)", func(m *models.Maze) float64 { return float64(m.Rows * m.Cols) }},
+	"density": {"(CAST(WallsCount AS REAL) / (Rows * Cols))", (*models.Maze).Density},
+	"minPath": {"MinPathLen", func(m *models.Maze) float64 { return float64(m.MinPathLen) }},
+	"maxPath": {"MaxPathLen", func(m *models.Maze) float64 { return float64(m.MaxPathLen) }},
+}
+
+// searchMazes performs mazes search within the condition (all mazes if empty),
+// returns the page and the next page cursor
+func (c App) searchMazes(q models.MazeQuery, where string, args ...interface{}) ([]*models.Maze, string, error) {
 	query := c.Db.SqlStatementBuilder.Select("*").From("Maze")
-	if ownerID != 0 {
-		query = query.Where("OwnerID=?", ownerID)
+	if where != "" {
+		query = query.Where("(" + where + ")", args...)
+	}
+
+	// filters
+	if q.MinSize != "" {
+		rows, cols := models.ParseGridSize(q.MinSize)
+		query = query.Where("Rows>=? AND Cols>=?", rows, cols)
 	}
+	if q.MaxSize != "" {
+		rows, cols := models.ParseGridSize(q.MaxSize)
+		query = query.Where("Rows<=? AND Cols<=?", rows, cols)
+	}
+	if q.MinDensity > 0 {
+		query = query.Where(mazeSortKeys["density"].expr + ">=?", q.MinDensity)
+	}
+	if q.MaxDensity > 0 {
+		query = query.Where(mazeSortKeys["density"].expr + "<=?", q.MaxDensity)
+	}
+	switch q.HasSolution {
+		case "true": query = query.Where("MaxPathOptimal=?", true)
+		case "false": query = query.Where("MaxPathOptimal=?", false)
+	}
+	if q.MinPathFrom > 0 {
+		query = query.Where("MinPathLen>=?", q.MinPathFrom)
+	}
+	if q.MinPathTo > 0 {
+		query = query.Where("MinPathLen<=?", q.MinPathTo)
+	}
+	if !q.CreatedAfterTime.IsZero() {
+		query = query.Where("Created>?", q.CreatedAfterTime.Unix())
+	}
+
+	// keyset pagination: items after the cursor in (sort key, id) order
+	key, order, cmp := mazeSortKeys[strings.TrimPrefix(q.Sort, "-")], "ASC", ">"
+	if q.Sort == "" {
+		key = mazeSortKeys["id"]
+	}
+	if strings.HasPrefix(q.Sort, "-") {
+		order, cmp = "DESC", "<"
+	}
+	if q.Cursor != "" {
+		cursor, _ := models.DecodeMazeCursor(q.Cursor)
+		query = query.Where(
+			fmt.Sprintf("(%s%s? OR (%s=? AND ID%s?))", key.expr, cmp, key.expr, cmp),
+			cursor.Value, cursor.Value, cursor.ID,
+		)
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = models.DefaultSearchLimit
+	}
+	query = query.OrderBy(key.expr + " " + order, "ID " + order).Limit(uint64(limit + 1))
 
 	var mazes []*models.Maze
 	_, err := c.Txn.Select(&mazes, query)
 
 	if err == sql.ErrNoRows {
 		// not found
-		return nil, nil
+		return nil, "", nil
 	}
 	if err != nil {
 		c.Log.Error("Failed to search mazes", "error", err)
+		return nil, "", err
+	}
+
+	next := ""
+	if len(mazes) > limit {
+		mazes = mazes[:limit]
+		last := mazes[limit - 1]
+		next = models.MazeCursor{Sort: q.Sort, Value: key.value(last), ID: last.ID}.Encode()
 	}
-	return mazes, err
+	return mazes, next, nil
 }
@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"database/sql"
+
+	"github.com/revel/revel"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// orgRoles orders organization roles, each one includes the previous ones
+var orgRoles = map[string]int{
+	models.OrgRoleViewer: 1,
+	models.OrgRoleEditor: 2,
+	models.OrgRoleOwner:  3,
+}
+
+// orgMazePermissions maps organization roles to the org mazes permissions
+var orgMazePermissions = map[string]string{
+	models.OrgRoleViewer: models.PermissionRead,
+	models.OrgRoleEditor: models.PermissionEdit,
+	models.OrgRoleOwner:  permissionOwner,
+}
+
+// Org controller
+type Org struct {
+	App
+}
+
+// List returns organizations of the user
+// swagger:route GET /org org listOrgs
+//
+// Returns organizations the user is a member of with the user roles
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: OrgListResponse
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Org) List() revel.Result {
+	user, _ := c.Session.Get("user")
+
+	orgs := []*models.Org{}
+	_, err := c.Txn.Select(&orgs, c.Db.SqlStatementBuilder.
+		Select("Org.*", "OrgMember.Role").From("Org").
+		Join("OrgMember ON OrgMember.OrgID = Org.ID").
+		Where("OrgMember.UserID=?", user.(*models.User).ID).OrderBy("Org.ID"))
+	if err != nil {
+		c.Log.Errorf("orgs lookup: %v", err)
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.OrgListResponse{OK: true, Items: orgs})
+}
+
+// Create creates an organization
+// swagger:route POST /org org createOrg
+//
+// Creates an organization, the user becomes its owner
+//
+//     Parameters:
+//     + name: org
+//       in: body
+//       description: Organization data
+//       required: true
+//       type: OrgRequest
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: OrgResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Org) Create(org models.OrgRequest) revel.Result {
+	user, _ := c.Session.Get("user")
+
+	org.Validate(c.Validation)
+	if ! c.Validation.HasErrors() {
+		// ensure name not taken
+		count, err := c.Txn.SelectInt(c.Db.SqlStatementBuilder.Select("COUNT(*)").From("Org").Where("Name=?", org.Name))
+		if err != nil {
+			c.Log.Errorf("org '%s' lookup: %v", org.Name, err)
+			return c.internalError()
+		}
+		if count > 0 {
+			c.Validation.Error("Already taken").Key("name")
+		}
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	item := &models.Org{Name: org.Name}
+	err := c.Txn.Insert(item)
+	if err == nil {
+		err = c.Txn.Insert(&models.OrgMember{OrgID: item.ID, UserID: user.(*models.User).ID, Role: models.OrgRoleOwner})
+	}
+	if err != nil {
+		c.Log.Errorf("org '%s' insert: %v", org.Name, err)
+		return c.internalError()
+	}
+
+	item.Role = models.OrgRoleOwner
+	return c.RenderJSON(models.OrgResponse{OK: true, Item: item})
+}
+
+// Delete deletes the organization
+// swagger:route DELETE /org/{orgId} org deleteOrg
+//
+// Deletes the organization with all its mazes (owners only)
+//
+//     Parameters:
+//     + name: orgId
+//       in: path
+//       description: Organization id
+//       required: true
+//       type: integer
+//       example: 1
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: OrgResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Org) Delete(id int64) revel.Result {
+	org, res := c.authorizeOrg(id, models.OrgRoleOwner)
+	if res != nil {
+		return res
+	}
+
+	// org data cascade
+	statements := []string{
+		"DELETE FROM MazeShare WHERE MazeID IN (SELECT ID FROM Maze WHERE OrgID = ?)",
+		"DELETE FROM MazeCell WHERE MazeID IN (SELECT ID FROM Maze WHERE OrgID = ?)",
+		"DELETE FROM Maze WHERE OrgID = ?",
+		"DELETE FROM OrgMember WHERE OrgID = ?",
+		"DELETE FROM Org WHERE ID = ?",
+	}
+	for _, statement := range statements {
+		if _, err := c.Txn.GetMap().Exec(statement, org.ID); err != nil {
+			c.Log.Errorf("org %d delete: %v", org.ID, err)
+			return c.internalError()
+		}
+	}
+
+	return c.RenderJSON(models.OrgResponse{OK: true, Item: org})
+}
+
+// Members returns the organization members
+// swagger:route GET /org/{orgId}/members org listOrgMembers
+//
+// Returns the organization members with roles
+//
+//     Parameters:
+//     + name: orgId
+//       in: path
+//       description: Organization id
+//       required: true
+//       type: integer
+//       example: 1
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: OrgMemberListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Org) Members(id int64) revel.Result {
+	org, res := c.authorizeOrg(id, models.OrgRoleViewer)
+	if res != nil {
+		return res
+	}
+	return c.memberList(org)
+}
+
+// SetMember adds the organization member or changes the member role
+// swagger:route PUT /org/{orgId}/members/{userId} org setOrgMember
+//
+// Adds a member or changes the member role (owners only), the last owner can't be demoted
+//
+//     Parameters:
+//     + name: orgId
+//       in: path
+//       description: Organization id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: userId
+//       in: path
+//       description: User id
+//       required: true
+//       type: integer
+//       example: 2
+//     + name: member
+//       in: body
+//       description: Member role
+//       required: true
+//       type: OrgMemberRequest
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: OrgMemberListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Org) SetMember(id, userId int64, member models.OrgMemberRequest) revel.Result {
+	org, res := c.authorizeOrg(id, models.OrgRoleOwner)
+	if res != nil {
+		return res
+	}
+
+	member.Validate(c.Validation)
+	if user, err := c.Txn.Get(models.User{}, userId); err != nil {
+		c.Log.Errorf("user %d lookup: %v", userId, err)
+		return c.internalError()
+	} else if user == nil {
+		c.Validation.Error("Not found").Key("userId")
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+	if member.Role != models.OrgRoleOwner {
+		if res := c.keepOwner(org, userId); res != nil {
+			return res
+		}
+	}
+
+	item := &models.OrgMember{OrgID: org.ID, UserID: userId, Role: member.Role}
+	updated, err := c.Txn.Update(item)
+	if err == nil && updated == 0 {
+		err = c.Txn.Insert(item)
+	}
+	if err != nil {
+		c.Log.Errorf("org %d member %d update: %v", org.ID, userId, err)
+		return c.internalError()
+	}
+	return c.memberList(org)
+}
+
+// RemoveMember removes the organization member
+// swagger:route DELETE /org/{orgId}/members/{userId} org removeOrgMember
+//
+// Removes a member (owners only) or leaves the organization, the last owner can't be removed
+//
+//     Parameters:
+//     + name: orgId
+//       in: path
+//       description: Organization id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: userId
+//       in: path
+//       description: User id
+//       required: true
+//       type: integer
+//       example: 2
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: OrgMemberListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Org) RemoveMember(id, userId int64) revel.Result {
+	org, res := c.authorizeOrg(id, models.OrgRoleViewer)
+	if res != nil {
+		return res
+	}
+	user, _ := c.Session.Get("user")
+	if userId != user.(*models.User).ID && org.Role != models.OrgRoleOwner {
+		return c.permissionError(models.OrgRoleOwner)
+	}
+	if res := c.keepOwner(org, userId); res != nil {
+		return res
+	}
+
+	deleted, err := c.Txn.Delete(&models.OrgMember{OrgID: org.ID, UserID: userId})
+	if err != nil {
+		c.Log.Errorf("org %d member %d delete: %v", org.ID, userId, err)
+		return c.internalError()
+	}
+	if deleted == 0 {
+		c.Validation.Error("Not found").Key("userId")
+		return c.validationError(c.Validation.Errors)
+	}
+	return c.memberList(org)
+}
+
+// memberList returns JSON response with the organization members
+func (c Org) memberList(org *models.Org) revel.Result {
+	items := []*models.OrgMember{}
+	_, err := c.Txn.Select(&items, c.Db.SqlStatementBuilder.
+		Select("OrgMember.*", "User.Username").From("OrgMember").
+		Join("User ON User.ID = OrgMember.UserID").
+		Where("OrgMember.OrgID=?", org.ID).OrderBy("OrgMember.UserID"))
+	if err != nil {
+		c.Log.Errorf("org %d members lookup: %v", org.ID, err)
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.OrgMemberListResponse{OK: true, Items: items})
+}
+
+// keepOwner ensures the organization keeps an owner after the user role change or removal
+func (c Org) keepOwner(org *models.Org, userID int64) revel.Result {
+	owners, err := c.Txn.SelectInt(c.Db.SqlStatementBuilder.Select("COUNT(*)").From("OrgMember").
+		Where("OrgID=? AND Role=? AND UserID<>?", org.ID, models.OrgRoleOwner, userID))
+	if err != nil {
+		c.Log.Errorf("org %d owners lookup: %v", org.ID, err)
+		return c.internalError()
+	}
+
+	if owners == 0 {
+		c.Validation.Error("Organization should keep an owner").Key("userId")
+		return c.validationError(c.Validation.Errors)
+	}
+	return nil
+}
+
+// authorizeOrg performs organization lookup by id and checks the user role, the role is set in the result.
+// Returns error result if the organization is not found (or the user is not a member) or the role is insufficient
+func (c App) authorizeOrg(id int64, role string) (*models.Org, revel.Result) {
+	user, err := c.Session.Get("user")
+	if user == nil || err != nil {
+		// user should be injected in the auth interceptor
+		return nil, c.internalError()
+	}
+
+	org := &models.Org{}
+	err = c.Txn.SelectOne(org, c.Db.SqlStatementBuilder.
+		Select("Org.*", "OrgMember.Role").From("Org").
+		Join("OrgMember ON OrgMember.OrgID = Org.ID").
+		Where("Org.ID=? AND OrgMember.UserID=?", id, user.(*models.User).ID))
+	if err == sql.ErrNoRows {
+		// organizations are not disclosed to non-members
+		c.Validation.Error("Not found").Key("id")
+		return nil, c.validationError(c.Validation.Errors)
+	}
+	if err != nil {
+		c.Log.Errorf("org %d lookup: %v", id, err)
+		return nil, c.internalError()
+	}
+
+	if orgRoles[org.Role] < orgRoles[role] {
+		return nil, c.permissionError(role)
+	}
+	return org, nil
+}
+
+// orgRole returns the user role in the organization, empty if not a member
+func (c App) orgRole(orgID, userID int64) (string, error) {
+	member := &models.OrgMember{}
+	err := c.Txn.SelectOne(member, c.Db.SqlStatementBuilder.Select("*").From("OrgMember").
+		Where("OrgID=? AND UserID=?", orgID, userID))
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		c.Log.Errorf("org %d member lookup: %v", orgID, err)
+		return "", err
+	}
+	return member.Role, nil
+}
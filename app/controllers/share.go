@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"database/sql"
+
+	"github.com/revel/revel"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// permissionOwner is required for maze deletion, visibility and share list changes
+const permissionOwner = "owner"
+
+// mazePermissions orders maze permissions, each one includes the previous ones
+var mazePermissions = map[string]int{
+	models.PermissionRead: 1,
+	models.PermissionEdit: 2,
+	permissionOwner:       3,
+}
+
+// Shares returns the maze share list
+// swagger:route GET /maze/{mazeId}/shares maze listMazeShares
+//
+// Returns users with access to the maze (owner or org owners only)
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: MazeShareListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Shares(id int64) revel.Result {
+	maze, res := c.authorizeMaze(id, permissionOwner)
+	if res != nil {
+		return res
+	}
+	return c.shareList(maze)
+}
+
+// Share grants the user read or edit access to the maze
+// swagger:route PUT /maze/{mazeId}/shares/{userId} maze shareMaze
+//
+// Grants the user read or edit access (owner or org owners only), effective for shared and public mazes
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: userId
+//       in: path
+//       description: User id
+//       required: true
+//       type: integer
+//       example: 2
+//     + name: share
+//       in: body
+//       description: Permission
+//       required: true
+//       type: MazeShareRequest
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: MazeShareListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Share(id, userId int64, share models.MazeShareRequest) revel.Result {
+	maze, res := c.authorizeMaze(id, permissionOwner)
+	if res != nil {
+		return res
+	}
+
+	share.Validate(c.Validation)
+	if userId == maze.OwnerID && maze.OrgID == 0 {
+		c.Validation.Error("Owner has full access").Key("userId")
+	} else if user, err := c.Txn.Get(models.User{}, userId); err != nil {
+		c.Log.Errorf("user %d lookup: %v", userId, err)
+		return c.internalError()
+	} else if user == nil {
+		c.Validation.Error("Not found").Key("userId")
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	existing, err := c.Txn.Get(models.MazeShare{}, maze.ID, userId)
+	if err != nil {
+		c.Log.Errorf("maze '%d' share lookup: %v", maze.ID, err)
+		return c.internalError()
+	}
+
+	item := &models.MazeShare{MazeID: maze.ID, UserID: userId, Permission: share.Permission}
+	if existing != nil {
+		_, err = c.Txn.Update(item)
+	} else {
+		err = c.Txn.Insert(item)
+	}
+	if err != nil {
+		c.Log.Errorf("maze '%d' share: %v", maze.ID, err)
+		return c.internalError()
+	}
+
+	user, _ := c.Session.Get("user")
+	c.audit(user.(*models.User), models.AuditMazeShare, models.AuditTargetMaze, maze.ID, models.NewAuditDiff(existing, item))
+	return c.shareList(maze)
+}
+
+// Unshare revokes the user access to the maze
+// swagger:route DELETE /maze/{mazeId}/shares/{userId} maze unshareMaze
+//
+// Revokes the user access (owner or org owners only)
+//
+//     Parameters:
+//     + name: mazeId
+//       in: path
+//       description: Maze id
+//       required: true
+//       type: integer
+//       example: 1
+//     + name: userId
+//       in: path
+//       description: User id
+//       required: true
+//       type: integer
+//       example: 2
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: MazeShareListResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c Maze) Unshare(id, userId int64) revel.Result {
+	maze, res := c.authorizeMaze(id, permissionOwner)
+	if res != nil {
+		return res
+	}
+
+	existing, err := c.Txn.Get(models.MazeShare{}, maze.ID, userId)
+	if err != nil {
+		c.Log.Errorf("maze '%d' share lookup: %v", maze.ID, err)
+		return c.internalError()
+	}
+	if existing == nil {
+		c.Validation.Error("Not found").Key("userId")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	if _, err := c.Txn.Delete(existing); err != nil {
+		c.Log.Errorf("maze '%d' unshare: %v", maze.ID, err)
+		return c.internalError()
+	}
+
+	user, _ := c.Session.Get("user")
+	c.audit(user.(*models.User), models.AuditMazeUnshare, models.AuditTargetMaze, maze.ID, models.NewAuditDiff(existing, nil))
+	return c.shareList(maze)
+}
+
+// shareList returns JSON response with the maze share list
+func (c Maze) shareList(maze *models.Maze) revel.Result {
+	items := []*models.MazeShare{}
+	_, err := c.Txn.Select(&items, c.Db.SqlStatementBuilder.
+		Select("MazeShare.*", "User.Username").From("MazeShare").
+		Join("User ON User.ID = MazeShare.UserID").
+		Where("MazeShare.MazeID=?", maze.ID).OrderBy("MazeShare.UserID"))
+	if err != nil {
+		c.Log.Errorf("maze '%d' shares lookup: %v", maze.ID, err)
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.MazeShareListResponse{OK: true, Visibility: maze.Visibility, Items: items})
+}
+
+// authorizeMaze performs maze lookup by id and checks the user permission, used by all maze actions.
+// Returns error result if the maze is not found (or not accessible at all) or the permission is not granted
+func (c Maze) authorizeMaze(id int64, permission string) (*models.Maze, revel.Result) {
+	user, err := c.Session.Get("user")
+	if user == nil || err != nil {
+		// user should be injected in the auth interceptor
+		return nil, c.internalError()
+	}
+
+	if id == 0 {
+		c.Validation.Error("Missing or incorrect maze id").Key("id")
+		return nil, c.validationError(c.Validation.Errors)
+	}
+
+	maze, err := c.getMaze(id)
+	if err != nil {
+		return nil, c.internalError()
+	}
+	granted := ""
+	if maze != nil {
+		if granted, err = c.mazePermission(maze, user.(*models.User).ID); err != nil {
+			return nil, c.internalError()
+		}
+	}
+
+	if granted == "" {
+		// inaccessible mazes are not disclosed
+		c.Validation.Error("Not found").Key("id")
+		return nil, c.validationError(c.Validation.Errors)
+	}
+	if mazePermissions[granted] < mazePermissions[permission] {
+		return nil, c.permissionError(permission)
+	}
+	return maze, nil
+}
+
+// authorizeVisibility checks that the maze visibility is changed by the owner only
+func (c Maze) authorizeVisibility(maze *models.Maze, visibility string) revel.Result {
+	if visibility == maze.Visibility {
+		return nil
+	}
+	user, _ := c.Session.Get("user")
+	granted, err := c.mazePermission(maze, user.(*models.User).ID)
+	if err != nil {
+		return c.internalError()
+	}
+	if granted != permissionOwner {
+		return c.permissionError(permissionOwner)
+	}
+	return nil
+}
+
+// mazePermission returns the user permission for the maze, empty if not accessible.
+// Org mazes are owned by the org, the org members are granted permissions by their roles.
+func (c Maze) mazePermission(maze *models.Maze, userID int64) (string, error) {
+	granted := ""
+	if maze.OrgID != 0 {
+		role, err := c.orgRole(maze.OrgID, userID)
+		if err != nil {
+			return "", err
+		}
+		granted = orgMazePermissions[role]
+	} else if maze.OwnerID == userID {
+		granted = permissionOwner
+	}
+	if mazePermissions[granted] >= mazePermissions[models.PermissionEdit] || maze.Visibility == models.VisibilityPrivate {
+		// shares grant edit permission at most
+		return granted, nil
+	}
+
+	share := &models.MazeShare{}
+	err := c.Txn.SelectOne(share, c.Db.SqlStatementBuilder.Select("*").From("MazeShare").
+		Where("MazeID=? AND UserID=?", maze.ID, userID))
+	if err == nil {
+		if mazePermissions[share.Permission] > mazePermissions[granted] {
+			granted = share.Permission
+		}
+		return granted, nil
+	}
+	if err != sql.ErrNoRows {
+		c.Log.Errorf("maze '%d' share lookup: %v", maze.ID, err)
+		return "", err
+	}
+
+	if granted == "" && maze.Visibility == models.VisibilityPublic {
+		return models.PermissionRead, nil
+	}
+	return granted, nil
+}
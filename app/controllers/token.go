@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/revel/revel"
+
+	"github.com/mkulish/mazes/app/auth"
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Refresh rotates the refresh token and issues new tokens
+// swagger:route POST /token/refresh user refreshToken
+//
+// Issues a new auth token and a new refresh token, the used refresh token becomes invalid.
+// Reuse of a rotated refresh token revokes all refresh tokens of the login.
+//
+//     Parameters:
+//     + name: refresh
+//       in: body
+//       description: Refresh token
+//       required: true
+//       type: TokenRefresh
+//
+//     Responses:
+//       200: LoginResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c App) Refresh(refresh models.TokenRefresh) revel.Result {
+	refresh.Validate(c.Validation)
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	token, err := c.getRefreshToken(refresh.RefreshToken)
+	if err != nil {
+		return c.internalError()
+	}
+	if token == nil || token.ExpiresAt < time.Now().Unix() {
+		return c.unauthorizedError()
+	}
+
+	// mark the token used, concurrent refreshes with the same token are treated as reuse
+	res, err := c.Txn.ExecUpdate(c.Db.SqlStatementBuilder.Update("RefreshToken").Set("Used", true).
+		Where("ID=? AND Used=?", token.ID, false))
+	if err != nil {
+		c.Log.Errorf("refresh token %d update: %v", token.ID, err)
+		return c.internalError()
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		c.Log.Warnf("refresh token %d reuse, revoking family of user %d", token.ID, token.UserID)
+		if err := c.revokeRefreshTokens(token.Family); err != nil {
+			return c.internalError()
+		}
+		c.audit(&models.User{ID: token.UserID}, models.AuditTokenReuse, models.AuditTargetUser, token.UserID, nil)
+		return c.unauthorizedError()
+	}
+
+	user, err := c.Txn.Get(models.User{}, token.UserID)
+	if err != nil {
+		c.Log.Errorf("user %d lookup: %v", token.UserID, err)
+		return c.internalError()
+	}
+	if user == nil {
+		return c.unauthorizedError()
+	}
+	if user.(*models.User).Disabled {
+		return c.disabledError()
+	}
+
+	c.audit(user.(*models.User), models.AuditTokenRefresh, models.AuditTargetUser, token.UserID, nil)
+	return c.loginResponse(user.(*models.User), token.Family)
+}
+
+// Logout revokes the auth token and the refresh token (if any)
+// swagger:route POST /logout user logout
+//
+// Revokes the auth token immediately, the refresh token from the body revokes all refresh tokens of the login.
+//
+//     Parameters:
+//     + name: refresh
+//       in: body
+//       description: Refresh token (optional)
+//       required: false
+//       type: TokenRefresh
+//
+//     Security:
+//       oauth2:
+//
+//     Responses:
+//       200: LogoutResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       500: InternalError
+func (c User) Logout(refresh models.TokenRefresh) revel.Result {
+	user, err := c.Session.Get("user")
+	if user == nil || err != nil {
+		// user should be injected in the auth interceptor
+		return c.internalError()
+	}
+	claims, found := c.Args["token"].(jwt.MapClaims)
+	if !found {
+		c.Validation.Error("Only auth tokens could be logged out, API keys should be deleted").Key("Authorization")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	exp, _ := claims["exp"].(float64)
+	if err := c.revokeToken(claims["jti"].(string), int64(exp)); err != nil {
+		return c.internalError()
+	}
+
+	if refresh.RefreshToken != "" {
+		token, err := c.getRefreshToken(refresh.RefreshToken)
+		if err != nil {
+			return c.internalError()
+		}
+		if token != nil && token.UserID == user.(*models.User).ID {
+			if err := c.revokeRefreshTokens(token.Family); err != nil {
+				return c.internalError()
+			}
+		}
+	}
+
+	c.audit(user.(*models.User), models.AuditLogout, models.AuditTargetUser, user.(*models.User).ID, nil)
+	return c.RenderJSON(models.LogoutResponse{OK: true})
+}
+
+// loginResponse issues auth and refresh tokens, empty family starts a new login
+func (c App) loginResponse(user *models.User, family string) revel.Result {
+	if family == "" {
+		family = auth.NewTokenID()
+	}
+
+	refreshToken, hash := auth.NewRefreshToken()
+	token := &models.RefreshToken{
+		UserID:    user.ID,
+		Hash:      hash,
+		Family:    family,
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL).Unix(),
+	}
+	if err := c.Txn.Insert(token); err != nil {
+		c.Log.Errorf("user '%s' refresh token insert: %v", user.Username, err)
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.LoginResponse{
+		OK:           true,
+		Token:        encodeToken(user, auth.NewTokenID(), auth.DefaultScope, time.Now().Add(auth.AccessTokenTTL)),
+		ExpiresIn:    int64(auth.AccessTokenTTL / time.Second),
+		RefreshToken: refreshToken,
+	})
+}
+
+// getRefreshToken performs refresh token lookup by hash
+func (c App) getRefreshToken(refreshToken string) (*models.RefreshToken, error) {
+	var tokens []*models.RefreshToken
+	_, err := c.Txn.Select(&tokens, c.Db.SqlStatementBuilder.Select("*").From("RefreshToken").
+		Where("Hash=?", auth.HashToken(refreshToken)))
+	if err != nil {
+		c.Log.Errorf("refresh token lookup: %v", err)
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return tokens[0], nil
+}
+
+// revokeRefreshTokens marks all refresh tokens of the family used and drops expired tokens
+func (c App) revokeRefreshTokens(family string) error {
+	_, err := c.Txn.ExecUpdate(c.Db.SqlStatementBuilder.Update("RefreshToken").Set("Used", true).Where("Family=?", family))
+	if err == nil {
+		_, err = c.Txn.GetMap().Exec("DELETE FROM RefreshToken WHERE ExpiresAt < ?", time.Now().Unix())
+	}
+	if err != nil {
+		c.Log.Errorf("refresh tokens revoke: %v", err)
+	}
+	return err
+}
+
+// revokeToken adds the auth token ID to the revocation list until it expires, drops expired entries.
+// Personal access tokens with the ID are dropped as well.
+func (c App) revokeToken(jti string, expiresAt int64) error {
+	err := c.Txn.Insert(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt})
+	if err == nil {
+		_, err = c.Txn.GetMap().Exec("DELETE FROM RevokedToken WHERE ExpiresAt < ?", time.Now().Unix())
+	}
+	if err == nil {
+		_, err = c.Txn.GetMap().Exec("DELETE FROM AccessToken WHERE JTI = ? OR ExpiresAt < ?", jti, time.Now().Unix())
+	}
+	if err != nil {
+		c.Log.Errorf("token %s revoke: %v", jti, err)
+	}
+	return err
+}
+
+// tokenRevoked checks the auth token ID in the revocation list
+func (c App) tokenRevoked(jti string) (bool, error) {
+	count, err := c.Txn.SelectInt(c.Db.SqlStatementBuilder.Select("COUNT(*)").From("RevokedToken").Where("JTI=?", jti))
+	if err != nil {
+		c.Log.Errorf("token %s revocation check: %v", jti, err)
+	}
+	return count > 0, err
+}
+
+// Tokens returns personal access tokens of the user
+// swagger:route GET /user/tokens user listAccessTokens
+//
+// Returns active personal access tokens (without the tokens themselves)
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: AccessTokenListResponse
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) Tokens() revel.Result {
+	user, _ := c.Session.Get("user")
+
+	tokens := []*models.AccessToken{}
+	_, err := c.Txn.Select(&tokens, c.Db.SqlStatementBuilder.Select("*").From("AccessToken").
+		Where("UserID=? AND ExpiresAt>=?", user.(*models.User).ID, time.Now().Unix()).OrderBy("ID"))
+	if err != nil {
+		c.Log.Errorf("access tokens lookup: %v", err)
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.AccessTokenListResponse{OK: true, Items: tokens})
+}
+
+// CreateToken issues a personal access token
+// swagger:route POST /user/tokens user createAccessToken
+//
+// Issues a scoped personal access token (e.g. read-only for CI dashboards).
+// The token is not refreshed and is valid until it expires or is deleted.
+//
+//     Parameters:
+//     + name: token
+//       in: body
+//       description: Token parameters
+//       required: true
+//       type: AccessTokenRequest
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: AccessTokenResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) CreateToken(request models.AccessTokenRequest) revel.Result {
+	user, _ := c.Session.Get("user")
+
+	request.Validate(c.Validation)
+	c.checkScope(request.Scope)
+	if request.ExpiresIn > int64(auth.PersonalTokenTTL / time.Second) {
+		c.Validation.Error("Max lifetime is %d seconds", int64(auth.PersonalTokenTTL / time.Second)).Key("expiresIn")
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	expiresIn := auth.PersonalTokenTTL
+	if request.ExpiresIn > 0 {
+		expiresIn = time.Duration(request.ExpiresIn) * time.Second
+	}
+	token := &models.AccessToken{
+		UserID:    user.(*models.User).ID,
+		JTI:       auth.NewTokenID(),
+		Name:      request.Name,
+		Scope:     auth.JoinScope(auth.ParseScope(request.Scope)),
+		ExpiresAt: time.Now().Add(expiresIn).Unix(),
+	}
+	if err := c.Txn.Insert(token); err != nil {
+		c.Log.Errorf("access token insert: %v", err)
+		return c.internalError()
+	}
+	c.audit(user.(*models.User), models.AuditTokenCreate, models.AuditTargetToken, token.ID, models.NewAuditDiff(nil, token))
+
+	return c.RenderJSON(models.AccessTokenResponse{
+		OK:    true,
+		Token: encodeToken(user.(*models.User), token.JTI, token.Scope, time.Unix(token.ExpiresAt, 0)),
+		Item:  token,
+	})
+}
+
+// DeleteToken revokes a personal access token
+// swagger:route DELETE /user/tokens/{tokenId} user deleteAccessToken
+//
+// Revokes a personal access token immediately
+//
+//     Parameters:
+//     + name: tokenId
+//       in: path
+//       description: Token id
+//       required: true
+//       type: integer
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: LogoutResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) DeleteToken(id int64) revel.Result {
+	user, _ := c.Session.Get("user")
+
+	var tokens []*models.AccessToken
+	_, err := c.Txn.Select(&tokens, c.Db.SqlStatementBuilder.Select("*").From("AccessToken").
+		Where("ID=? AND UserID=?", id, user.(*models.User).ID))
+	if err != nil {
+		c.Log.Errorf("access token %d lookup: %v", id, err)
+		return c.internalError()
+	}
+	if len(tokens) == 0 {
+		c.Validation.Error("Not found").Key("id")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	if err := c.revokeToken(tokens[0].JTI, tokens[0].ExpiresAt); err != nil {
+		return c.internalError()
+	}
+	c.audit(user.(*models.User), models.AuditTokenDelete, models.AuditTargetToken, id, models.NewAuditDiff(tokens[0], nil))
+	return c.RenderJSON(models.LogoutResponse{OK: true})
+}
+
+// checkScope validates requested scope, it should be within the scope of the current request
+func (c App) checkScope(requested string) {
+	current, _ := c.Args["scope"].(string)
+	for _, scope := range auth.ParseScope(requested) {
+		if !auth.HasScope(auth.DefaultScope, scope) {
+			c.Validation.Error("Should be one of: %s", strings.Join(auth.Scopes, ", ")).Key("scope")
+		} else if !auth.HasScope(current, scope) {
+			c.Validation.Error("Should be within the current scope: %s", current).Key("scope")
+		}
+	}
+}
+
+// revokeUserTokens deletes refresh tokens and personal access tokens of the user,
+// auth tokens are rejected by the user tokens version. Deleted refresh tokens are unknown
+// to Refresh, unlike used ones they are not treated as reuse
+func (c App) revokeUserTokens(userID int64) error {
+	_, err := c.Txn.GetMap().Exec("DELETE FROM RefreshToken WHERE UserID = ?", userID)
+	if err == nil {
+		_, err = c.Txn.GetMap().Exec("DELETE FROM AccessToken WHERE UserID = ?", userID)
+	}
+	if err != nil {
+		c.Log.Errorf("user %d tokens revoke: %v", userID, err)
+	}
+	return err
+}
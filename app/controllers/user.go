@@ -2,14 +2,18 @@ package controllers
 
 import (
 	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/revel/revel"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/mkulish/mazes/app/auth"
 	"github.com/mkulish/mazes/app/models"
 )
 
-var hmacSecret = []byte("demo")
+// dummyHash is compared with passwords of unknown users to keep the response time
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
 
 // User controller
 type User struct {
@@ -57,13 +61,16 @@ func (c App) Register(user models.User) revel.Result {
 		return c.internalError()
 	}
 
-	return c.RenderJSON(models.LoginResponse{OK: true, Token: encodeToken(&user)})
+	c.audit(&user, models.AuditRegister, models.AuditTargetUser, user.ID, nil)
+	return c.loginResponse(&user, "")
 }
 
-// Login performs login and returns JWT token
+// Login performs login and returns JWT auth and refresh tokens,
+// failed attempts are throttled by username and client IP
 // swagger:route POST /login user login
 //
-// Performs login
+// Performs login. Failed attempts are delayed exponentially and temporarily locked
+// by username and client IP, the error does not tell if the username exists.
 //
 //     Parameters:
 //     + name: user
@@ -75,29 +82,237 @@ func (c App) Register(user models.User) revel.Result {
 //     Responses:
 //       200: LoginResponse
 //       400: ValidationError
+//       403: ForbiddenError
+//       429: TooManyRequestsError
 //       500: InternalError
 func (c App) Login(loginData models.User) revel.Result {
-	var user *models.User
-	var err error
-
 	loginData.Validate(c.Validation)
-	if ! c.Validation.HasErrors() {
-		// user lookup and password check
-		if user, err = c.getUser(loginData.Username); err != nil {
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	// attempts throttling by username and client IP, counted before the password check
+	// so that concurrent attempts can't exceed the limits
+	now := time.Now()
+	limits := []struct {
+		key      string
+		throttle auth.Throttle
+	}{
+		{"user:" + loginData.Username, auth.UsernameThrottle},
+		{"ip:" + c.ClientIP, auth.IPThrottle},
+	}
+	for i, limit := range limits {
+		wait, err := auth.DefaultLimiter.Attempt(limit.key, limit.throttle, now)
+		if err != nil {
+			c.Log.Errorf("login limiter '%s': %v", limit.key, err)
 			return c.internalError()
 		}
-		if user == nil {
-			c.Validation.Error("Not found").Key("username")
-		} else if bcrypt.CompareHashAndPassword(user.HashedPassword, []byte(loginData.Password)) != nil {
-			c.Validation.Error("Incorrect username or password").Key("password")
+		if wait > 0 {
+			// the attempt is not made, already counted keys are released
+			for _, counted := range limits[:i] {
+				if err := auth.DefaultLimiter.Release(counted.key); err != nil {
+					c.Log.Errorf("login limiter '%s': %v", counted.key, err)
+				}
+			}
+			return c.tooManyRequestsError(wait)
 		}
 	}
 
+	user, err := c.getUser(loginData.Username)
+	if err != nil {
+		return c.internalError()
+	}
+
+	// unknown usernames are checked against a dummy hash, the response is the same
+	hash := dummyHash
+	if user != nil {
+		hash = user.HashedPassword
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(loginData.Password)) != nil || user == nil {
+		// failed attempts are recorded with the attempted username and the account (if any) as the target
+		var target int64
+		if user != nil {
+			target = user.ID
+		}
+		c.audit(&models.User{Username: loginData.Username}, models.AuditLoginFailure, models.AuditTargetUser, target, nil)
+		c.Validation.Error("Incorrect username or password").Key("password")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	// successful attempt clears the username failures, other users failures from the IP are kept
+	if err := auth.DefaultLimiter.Reset(limits[0].key); err != nil {
+		c.Log.Errorf("login limiter '%s': %v", limits[0].key, err)
+	}
+	if err := auth.DefaultLimiter.Release(limits[1].key); err != nil {
+		c.Log.Errorf("login limiter '%s': %v", limits[1].key, err)
+	}
+	if user.Disabled {
+		c.audit(&models.User{Username: loginData.Username}, models.AuditLoginFailure, models.AuditTargetUser, user.ID, nil)
+		return c.disabledError()
+	}
+	c.audit(user, models.AuditLogin, models.AuditTargetUser, user.ID, nil)
+	return c.loginResponse(user, "")
+}
+
+// Me returns the user profile
+// swagger:route GET /user/me user getProfile
+//
+// Returns the current user profile
+//
+//     Security:
+//       oauth2: read
+//
+//     Responses:
+//       200: UserResponse
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) Me() revel.Result {
+	user, _ := c.Session.Get("user")
+	u := user.(*models.User)
+
+	mazes, err := c.Txn.SelectInt(c.Db.SqlStatementBuilder.Select("COUNT(*)").From("Maze").Where("OwnerID=? AND OrgID=0", u.ID))
+	if err != nil {
+		c.Log.Errorf("user %d mazes count: %v", u.ID, err)
+		return c.internalError()
+	}
+
+	return c.RenderJSON(models.UserResponse{OK: true, ID: u.ID, Username: u.Username, Created: u.Created, Mazes: mazes, Role: u.Role})
+}
+
+// ChangePassword changes the user password
+// swagger:route PUT /user/me/password user changePassword
+//
+// Changes the password, all auth, refresh and personal access tokens are revoked
+// (API keys are kept), new tokens are returned for the current client.
+//
+//     Parameters:
+//     + name: password
+//       in: body
+//       description: Current and new passwords
+//       required: true
+//       type: PasswordChange
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: LoginResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) ChangePassword(change models.PasswordChange) revel.Result {
+	user, _ := c.Session.Get("user")
+	u := user.(*models.User)
+
+	change.Validate(c.Validation)
+	if ! c.Validation.HasErrors() && bcrypt.CompareHashAndPassword(u.HashedPassword, []byte(change.OldPassword)) != nil {
+		c.Validation.Error("Incorrect password").Key("oldPassword")
+	}
+	if c.Validation.HasErrors() {
+		return c.validationError(c.Validation.Errors)
+	}
+
+	u.HashedPassword, _ = bcrypt.GenerateFromPassword([]byte(change.NewPassword), bcrypt.DefaultCost)
+	u.TokenVersion++
+	if _, err := c.Txn.Update(u); err != nil {
+		c.Log.Errorf("user %d password update: %v", u.ID, err)
+		return c.internalError()
+	}
+	if err := c.revokeUserTokens(u.ID); err != nil {
+		return c.internalError()
+	}
+
+	c.audit(u, models.AuditPasswordChange, models.AuditTargetUser, u.ID, nil)
+	return c.loginResponse(u, "")
+}
+
+// DeleteAccount deletes the user with all personal mazes, memberships, tokens and API keys
+// swagger:route DELETE /user/me user deleteAccount
+//
+// Deletes the account with all personal mazes, org memberships, tokens and API keys,
+// requires the password confirmation. The last owner of an organization should
+// add another owner or delete the organization first.
+//
+//     Parameters:
+//     + name: confirmation
+//       in: body
+//       description: Password confirmation
+//       required: true
+//       type: AccountDeletion
+//
+//     Security:
+//       oauth2: write
+//
+//     Responses:
+//       200: LogoutResponse
+//       400: ValidationError
+//       401: UnauthorizedError
+//       403: ForbiddenError
+//       500: InternalError
+func (c User) DeleteAccount(confirmation models.AccountDeletion) revel.Result {
+	user, _ := c.Session.Get("user")
+	u := user.(*models.User)
+
+	confirmation.Validate(c.Validation)
+	if ! c.Validation.HasErrors() && bcrypt.CompareHashAndPassword(u.HashedPassword, []byte(confirmation.Password)) != nil {
+		c.Validation.Error("Incorrect password").Key("password")
+	}
 	if c.Validation.HasErrors() {
 		return c.validationError(c.Validation.Errors)
 	}
 
-	return c.RenderJSON(models.LoginResponse{OK: true, Token: encodeToken(user)})
+	// organizations should not be left without owners
+	var orgs []string
+	_, err := c.Txn.Select(&orgs, c.Db.SqlStatementBuilder.Select("Org.Name").From("Org").
+		Join("OrgMember ON OrgMember.OrgID = Org.ID").
+		Where("OrgMember.UserID=? AND OrgMember.Role=?", u.ID, models.OrgRoleOwner).
+		Where("NOT EXISTS (SELECT 1 FROM OrgMember o WHERE o.OrgID = Org.ID AND o.Role = ? AND o.UserID <> ?)", models.OrgRoleOwner, u.ID).
+		OrderBy("Org.Name"))
+	if err != nil {
+		c.Log.Errorf("user %d orgs lookup: %v", u.ID, err)
+		return c.internalError()
+	}
+	if len(orgs) > 0 {
+		c.Validation.Error("Last owner of organizations: %s", strings.Join(orgs, ", ")).Key("orgs")
+		return c.validationError(c.Validation.Errors)
+	}
+
+	// user data cascade, issued auth tokens are rejected once the user is deleted,
+	// org mazes created by the user are kept
+	if _, err := c.Txn.GetMap().Exec("DELETE FROM MazeShare WHERE UserID = ? OR MazeID IN (SELECT ID FROM Maze WHERE OwnerID = ? AND OrgID = 0)", u.ID, u.ID); err != nil {
+		c.Log.Errorf("user %d shares delete: %v", u.ID, err)
+		return c.internalError()
+	}
+	if _, err := c.Txn.GetMap().Exec("DELETE FROM MazeCell WHERE MazeID IN (SELECT ID FROM Maze WHERE OwnerID = ? AND OrgID = 0)", u.ID); err != nil {
+		c.Log.Errorf("user %d maze cells delete: %v", u.ID, err)
+		return c.internalError()
+	}
+	if _, err := c.Txn.GetMap().Exec("DELETE FROM Maze WHERE OwnerID = ? AND OrgID = 0", u.ID); err != nil {
+		c.Log.Errorf("user %d mazes delete: %v", u.ID, err)
+		return c.internalError()
+	}
+	cascade := []struct{ table, column string }{
+		{"OrgMember", "UserID"},
+		{"APIKey", "UserID"},
+		{"AccessToken", "UserID"},
+		{"RefreshToken", "UserID"},
+	}
+	for _, ref := range cascade {
+		if _, err := c.Txn.GetMap().Exec("DELETE FROM " + ref.table + " WHERE " + ref.column + " = ?", u.ID); err != nil {
+			c.Log.Errorf("user %d %s delete: %v", u.ID, ref.table, err)
+			return c.internalError()
+		}
+	}
+	if _, err := c.Txn.Delete(u); err != nil {
+		c.Log.Errorf("user %d delete: %v", u.ID, err)
+		return c.internalError()
+	}
+
+	// audit events of the user are kept
+	c.audit(u, models.AuditAccountDelete, models.AuditTargetUser, u.ID, nil)
+	return c.RenderJSON(models.LogoutResponse{OK: true})
 }
 
 // getUser performs user lookup by username
@@ -0,0 +1,218 @@
+package generator
+
+import (
+	"math/rand"
+)
+
+// Backtracker carves a random depth-first spanning tree (recursive backtracker),
+// produces long winding corridors with few dead ends
+func Backtracker(l *Lattice, rnd *rand.Rand) {
+	visited := make([]bool, l.Rooms())
+	stack := []int{rnd.Intn(l.Rooms())}
+	visited[stack[0]] = true
+
+	for len(stack) > 0 {
+		room := stack[len(stack) - 1]
+		var next []int
+		for _, n := range l.Neighbours(room) {
+			if !visited[n] {
+				next = append(next, n)
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack) - 1]
+			continue
+		}
+
+		n := next[rnd.Intn(len(next))]
+		l.Connect(room, n)
+		visited[n] = true
+		stack = append(stack, n)
+	}
+}
+
+// Prim grows the tree from a random frontier room (randomized Prim's algorithm),
+// produces many short dead ends
+func Prim(l *Lattice, rnd *rand.Rand) {
+	const (
+		outside = iota
+		frontier
+		inside
+	)
+	state := make([]int, l.Rooms())
+	var rooms []int
+	add := func(room int) {
+		state[room] = inside
+		for _, n := range l.Neighbours(room) {
+			if state[n] == outside {
+				state[n] = frontier
+				rooms = append(rooms, n)
+			}
+		}
+	}
+
+	add(rnd.Intn(l.Rooms()))
+	for len(rooms) > 0 {
+		i := rnd.Intn(len(rooms))
+		room := rooms[i]
+		rooms[i] = rooms[len(rooms) - 1]
+		rooms = rooms[:len(rooms) - 1]
+
+		var connected []int
+		for _, n := range l.Neighbours(room) {
+			if state[n] == inside {
+				connected = append(connected, n)
+			}
+		}
+		l.Connect(room, connected[rnd.Intn(len(connected))])
+		add(room)
+	}
+}
+
+// Kruskal joins random passages between disjoint trees (randomized Kruskal's algorithm)
+func Kruskal(l *Lattice, rnd *rand.Rand) {
+	var passages [][2]int
+	for room := 0; room < l.Rooms(); room++ {
+		for _, n := range l.Neighbours(room) {
+			if n > room {
+				passages = append(passages, [2]int{room, n})
+			}
+		}
+	}
+	rnd.Shuffle(len(passages), func(i, j int) {
+		passages[i], passages[j] = passages[j], passages[i]
+	})
+
+	// disjoint set forest of the trees
+	parents := make([]int, l.Rooms())
+	for room := range parents {
+		parents[room] = room
+	}
+	var find func(room int) int
+	find = func(room int) int {
+		if parents[room] != room {
+			parents[room] = find(parents[room])
+		}
+		return parents[room]
+	}
+
+	for _, p := range passages {
+		if a, b := find(p[0]), find(p[1]); a != b {
+			parents[a] = b
+			l.Connect(p[0], p[1])
+		}
+	}
+}
+
+// Wilson adds loop-erased random walks to the tree (Wilson's algorithm),
+// all spanning trees are equally likely
+func Wilson(l *Lattice, rnd *rand.Rand) {
+	inTree := make([]bool, l.Rooms())
+	inTree[rnd.Intn(l.Rooms())] = true
+
+	// the last step from each room of the walk, revisited rooms overwrite it erasing loops
+	next := make([]int, l.Rooms())
+	for _, start := range rnd.Perm(l.Rooms()) {
+		for room := start; !inTree[room]; room = next[room] {
+			neighbours := l.Neighbours(room)
+			next[room] = neighbours[rnd.Intn(len(neighbours))]
+		}
+		for room := start; !inTree[room]; room = next[room] {
+			inTree[room] = true
+			l.Connect(room, next[room])
+		}
+	}
+}
+
+// Eller carves the maze row by row keeping rooms connectivity sets (Eller's algorithm)
+func Eller(l *Lattice, rnd *rand.Rand) {
+	// set of each room in the current row, 0 - not assigned yet
+	sets := make([]int, l.Width)
+	lastSet := 0
+
+	for y := 0; y < l.Height; y++ {
+		for x := range sets {
+			if sets[x] == 0 {
+				lastSet++
+				sets[x] = lastSet
+			}
+		}
+
+		// randomly join adjacent rooms of different sets, the last row joins all of them
+		last := y == l.Height - 1
+		for x := 0; x < l.Width - 1; x++ {
+			if sets[x] == sets[x + 1] || (!last && rnd.Intn(2) == 0) {
+				continue
+			}
+			l.Connect(l.Index(x, y), l.Index(x + 1, y))
+			merged := sets[x + 1]
+			for i := range sets {
+				if sets[i] == merged {
+					sets[i] = sets[x]
+				}
+			}
+		}
+		if last {
+			break
+		}
+
+		// each set goes down at least once
+		var groups [][]int
+		group := map[int]int{}
+		for x, set := range sets {
+			if _, found := group[set]; !found {
+				group[set] = len(groups)
+				groups = append(groups, nil)
+			}
+			groups[group[set]] = append(groups[group[set]], x)
+		}
+
+		below := make([]int, l.Width)
+		for _, rooms := range groups {
+			rnd.Shuffle(len(rooms), func(i, j int) {
+				rooms[i], rooms[j] = rooms[j], rooms[i]
+			})
+			for i, x := range rooms {
+				if i == 0 || rnd.Intn(2) == 0 {
+					l.Connect(l.Index(x, y), l.Index(x, y + 1))
+					below[x] = sets[x]
+				}
+			}
+		}
+		sets = below
+	}
+}
+
+// BinaryTree connects each room either to the north or to the west neighbour,
+// produces open first row and column
+func BinaryTree(l *Lattice, rnd *rand.Rand) {
+	for room := 0; room < l.Rooms(); room++ {
+		var next []int
+		if room >= l.Width {
+			next = append(next, room - l.Width)
+		}
+		if room % l.Width > 0 {
+			next = append(next, room - 1)
+		}
+		if len(next) > 0 {
+			l.Connect(room, next[rnd.Intn(len(next))])
+		}
+	}
+}
+
+// Sidewinder carves random runs in each row connected to the north by a random room of the run,
+// produces open first row
+func Sidewinder(l *Lattice, rnd *rand.Rand) {
+	for y := 0; y < l.Height; y++ {
+		start := 0
+		for x := 0; x < l.Width; x++ {
+			if y > 0 && (x == l.Width - 1 || rnd.Intn(2) == 0) {
+				north := start + rnd.Intn(x - start + 1)
+				l.Connect(l.Index(north, y), l.Index(north, y - 1))
+				start = x + 1
+			} else if x < l.Width - 1 {
+				l.Connect(l.Index(x, y), l.Index(x + 1, y))
+			}
+		}
+	}
+}
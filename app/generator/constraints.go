@@ -0,0 +1,245 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/services"
+)
+
+// Budget limits constrained generation
+type Budget struct {
+	// Attempts is the max number of measured mazes, new seeds and mutations (0 - unlimited)
+	Attempts int
+	// Timeout is the max generation duration (0 - unlimited)
+	Timeout time.Duration
+}
+
+// DefaultBudget is used for constrained generation, overridden from app.conf on start
+var DefaultBudget = Budget{Attempts: 200, Timeout: 10 * time.Second}
+
+// longest path search budget of a single measurement
+var measureBudget = services.Budget{Nodes: 100000}
+
+// Result represents constrained generation result
+type Result struct {
+	// Seed of the generated maze, the same request with the seed reproduces the maze
+	Seed int64
+	// Attempts used
+	Attempts int
+	// Metrics of the generated maze
+	Metrics models.MazeMetrics
+	// MaxPathExact is set if the longest path is measured exactly (perfect mazes and max path constraints),
+	// otherwise the metric is the shortest path length
+	MaxPathExact bool
+}
+
+// GenerateConstrained generates mazes starting from the seed until the metrics are within the constraints.
+// Each seed maze is mutated by opening dead end walls while it helps to meet the constraints,
+// the error explains the closest miss when the budget is exhausted. Mazes with the longest path
+// not measured exactly within the search budget fail max path constraints.
+func GenerateConstrained(m *models.Maze, algorithm string, seed int64, c models.MazeConstraints, budget Budget) (Result, error) {
+	var deadline time.Time
+	if budget.Timeout > 0 {
+		deadline = time.Now().Add(budget.Timeout)
+	}
+	expired := func() bool {
+		return !deadline.IsZero() && time.Now().After(deadline)
+	}
+	res := Result{}
+	spent := func() bool {
+		return (budget.Attempts > 0 && res.Attempts >= budget.Attempts) || expired()
+	}
+
+	closest, closestDistance := "", math.Inf(1)
+	for s := seed; !spent(); s++ {
+		lay, err := carve(m, algorithm, s)
+		if err != nil {
+			return res, err
+		}
+		rnd := rand.New(rand.NewSource(s))
+
+		for perfect := true; ; perfect = false {
+			res.Attempts++
+			lay.apply(m)
+			longest := c.MaxPathFrom > 0 || c.MaxPathTo > 0
+			metrics, exact, err := lay.measure(m, perfect, longest)
+			if err != nil {
+				return res, err
+			}
+			if longest && !exact {
+				// the longest path could be longer than measured, braiding only adds loops to search
+				if closest == "" {
+					closest = "max path search budget exhausted"
+				}
+				break
+			}
+
+			misses := checkConstraints(c, metrics)
+			if len(misses) == 0 {
+				res.Seed, res.Metrics, res.MaxPathExact = s, metrics, exact
+				return res, nil
+			}
+			if distance := misses.distance(); distance < closestDistance {
+				closest, closestDistance = misses.String(), distance
+			}
+
+			if spent() || !misses.braidable() || !lay.braid(rnd) {
+				break
+			}
+		}
+	}
+
+	reason := fmt.Sprintf("%d attempts", res.Attempts)
+	if expired() {
+		reason = fmt.Sprintf("%d attempts (timeout)", res.Attempts)
+	}
+	return res, fmt.Errorf("Constraints not met in %s, closest maze: %s", reason, closest)
+}
+
+// measure returns the layout metrics and whether the longest path is exact, walls should be applied to the maze.
+// The longest path of a perfect maze is the only path, otherwise it is measured on request only.
+func (lay *layout) measure(m *models.Maze, perfect, longest bool) (models.MazeMetrics, bool, error) {
+	res := models.MazeMetrics{}
+
+	path, err := services.BFSPath(m)
+	if err != nil {
+		return res, false, err
+	}
+	res.MinPath, res.MaxPath = len(path) - 1, len(path) - 1
+	exact := perfect
+	if !perfect && longest {
+		path, exact, err = services.LongestPath(m, measureBudget)
+		if err != nil {
+			return res, false, err
+		}
+		res.MaxPath = len(path) - 1
+	}
+
+	open, junctions := 0, 0
+	for idx := range lay.open {
+		if !lay.open[idx] {
+			continue
+		}
+		open++
+		switch degree := lay.degree(idx); {
+		case degree == 1 && lay.deadEnd(idx):
+			res.DeadEnds++
+		case degree >= 3:
+			junctions++
+		}
+	}
+	res.Branching = float64(junctions) / float64(open)
+	return res, exact, nil
+}
+
+// deadEnd checks if the open cell with a single open neighbour is neither the entrance nor the exit
+func (lay *layout) deadEnd(idx int) bool {
+	return idx != lay.entrance && idx / lay.cols != lay.rows - 1
+}
+
+// braid opens a wall between a dead end and another passage making a loop,
+// the bottom row is never opened to keep the single exit. Returns false if there are no such walls.
+func (lay *layout) braid(rnd *rand.Rand) bool {
+	var walls []int
+	for idx := range lay.open {
+		if !lay.open[idx] || lay.degree(idx) != 1 || !lay.deadEnd(idx) {
+			continue
+		}
+		for _, w := range lay.neighbours(idx) {
+			// the cell behind the wall in the same direction
+			behind := 2 * w - idx
+			if lay.open[w] || w / lay.cols == lay.rows - 1 || behind < 0 || behind >= len(lay.open) {
+				continue
+			}
+			if (w % lay.cols - idx % lay.cols) != (behind % lay.cols - w % lay.cols) {
+				// wrapped around the row
+				continue
+			}
+			if lay.open[behind] {
+				walls = append(walls, w)
+			}
+		}
+	}
+	if len(walls) == 0 {
+		return false
+	}
+
+	lay.open[walls[rnd.Intn(len(walls))]] = true
+	return true
+}
+
+// miss represents a metric out of the constraint range
+type miss struct {
+	metric    string
+	value     float64
+	from, to  float64
+	// braiding (opening dead ends) moves the metric towards the range
+	braidable bool
+}
+
+type misses []miss
+
+// checkConstraints returns metrics out of the constraint ranges
+func checkConstraints(c models.MazeConstraints, metrics models.MazeMetrics) misses {
+	var res misses
+	check := func(metric string, value, from, to float64, braidDown bool) {
+		if value < from {
+			res = append(res, miss{metric, value, from, to, !braidDown})
+		} else if to > 0 && value > to {
+			res = append(res, miss{metric, value, from, to, braidDown})
+		}
+	}
+	// braiding removes dead ends and shortcuts paths, adds loops and junctions
+	check("min path", float64(metrics.MinPath), float64(c.MinPathFrom), float64(c.MinPathTo), true)
+	check("max path", float64(metrics.MaxPath), float64(c.MaxPathFrom), float64(c.MaxPathTo), false)
+	check("dead ends", float64(metrics.DeadEnds), float64(c.DeadEndsFrom), float64(c.DeadEndsTo), true)
+	check("branching", metrics.Branching, c.BranchingFrom, c.BranchingTo, false)
+	return res
+}
+
+// braidable checks if braiding could help with all misses
+func (ms misses) braidable() bool {
+	for _, m := range ms {
+		if !m.braidable {
+			return false
+		}
+	}
+	return true
+}
+
+// distance returns the sum of relative distances to the ranges
+func (ms misses) distance() float64 {
+	res := 0.0
+	for _, m := range ms {
+		bound := m.from
+		if m.value > m.from {
+			bound = m.to
+		}
+		res += math.Abs(m.value - bound) / math.Max(bound, 1)
+	}
+	return res
+}
+
+func (ms misses) String() string {
+	// branching is rounded to 3 decimal places
+	round := func(v float64) float64 {
+		return math.Round(v * 1000) / 1000
+	}
+
+	res := ""
+	for i, m := range ms {
+		if i > 0 {
+			res += ", "
+		}
+		if m.value < m.from {
+			res += fmt.Sprintf("%s %g is below %g", m.metric, round(m.value), m.from)
+		} else {
+			res += fmt.Sprintf("%s %g is above %g", m.metric, round(m.value), m.to)
+		}
+	}
+	return res
+}
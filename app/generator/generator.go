@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/services"
+)
+
+// DefaultAlgorithm is used when the generation algorithm is not set
+const DefaultAlgorithm = "backtracker"
+
+// Generator carves passages between lattice rooms
+type Generator interface {
+	// Carve connects all lattice rooms with a spanning tree of passages
+	Carve(l *Lattice, rnd *rand.Rand)
+}
+
+// Func is an adapter to use ordinary functions as generators
+type Func func(l *Lattice, rnd *rand.Rand)
+
+// Carve calls f(l, rnd)
+func (f Func) Carve(l *Lattice, rnd *rand.Rand) {
+	f(l, rnd)
+}
+
+// registered generators by algorithm name
+var generators = map[string]Generator{}
+
+func init() {
+	Register("backtracker", Func(Backtracker))
+	Register("prim", Func(Prim))
+	Register("kruskal", Func(Kruskal))
+	Register("wilson", Func(Wilson))
+	Register("eller", Func(Eller))
+	Register("binarytree", Func(BinaryTree))
+	Register("sidewinder", Func(Sidewinder))
+}
+
+// Register makes the generator available by algorithm name
+func Register(algorithm string, g Generator) {
+	generators[algorithm] = g
+}
+
+// Find returns registered generator (nil if not found)
+func Find(algorithm string) Generator {
+	return generators[algorithm]
+}
+
+// Algorithms returns sorted names of the registered generators
+func Algorithms() []string {
+	var res []string
+	for name := range generators {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Generate replaces maze walls with a perfect maze carved by the algorithm,
+// the same seed produces the same maze
+func Generate(m *models.Maze, algorithm string, seed int64) error {
+	lay, err := carve(m, algorithm, seed)
+	if err != nil {
+		return err
+	}
+
+	lay.apply(m)
+	return nil
+}
+
+// layout is a generated grid of open cells
+type layout struct {
+	rows, cols int
+	open       []bool
+	entrance   int
+}
+
+// carve generates a perfect maze layout. Rooms are aligned with the entrance cell,
+// the only exit is a passage from the last rooms row down to the bottom row.
+func carve(m *models.Maze, algorithm string, seed int64) (*layout, error) {
+	g := Find(algorithm)
+	if g == nil {
+		return nil, fmt.Errorf("Unknown algorithm: %s", algorithm)
+	}
+
+	rows, cols := models.ParseGridSize(m.GridSize)
+	x, y := services.CellCoords(m.Entrance)
+	if x < 0 || x >= cols || y < 0 || y >= rows {
+		return nil, fmt.Errorf("Incorrect entrance: %s", m.Entrance)
+	}
+	if y == rows - 1 {
+		return nil, fmt.Errorf("Entrance should be above the bottom row: %s", m.Entrance)
+	}
+
+	// rooms are placed on every other cell starting from the entrance parity,
+	// cells between adjacent rooms are either passages or walls
+	ox, oy := x % 2, y % 2
+	l := newLattice((cols - 1 - ox) / 2 + 1, (rows - 2 - oy) / 2 + 1)
+	rnd := rand.New(rand.NewSource(seed))
+	g.Carve(l, rnd)
+
+	lay := &layout{rows: rows, cols: cols, open: make([]bool, rows * cols), entrance: y * cols + x}
+	for room := 0; room < l.Rooms(); room++ {
+		cx, cy := ox + 2 * (room % l.Width), oy + 2 * (room / l.Width)
+		lay.open[cy * cols + cx] = true
+		if l.east[room] {
+			lay.open[cy * cols + cx + 1] = true
+		}
+		if l.south[room] {
+			lay.open[(cy + 1) * cols + cx] = true
+		}
+	}
+
+	exit := ox + 2 * rnd.Intn(l.Width)
+	for cy := oy + 2 * (l.Height - 1) + 1; cy < rows; cy++ {
+		lay.open[cy * cols + exit] = true
+	}
+	return lay, nil
+}
+
+// apply replaces maze walls with the layout walls, the exit is on the bottom row
+func (lay *layout) apply(m *models.Maze) {
+	m.Walls = []string{}
+	for idx, open := range lay.open {
+		if !open {
+			m.Walls = append(m.Walls, services.CellName(idx % lay.cols, idx / lay.cols))
+		}
+	}
+	m.Exit, m.ExitPolicy = "", ""
+}
+
+// neighbours returns horizontally and vertically adjacent cells
+func (lay *layout) neighbours(idx int) []int {
+	res := make([]int, 0, 4)
+	x, y := idx % lay.cols, idx / lay.cols
+	if x > 0 {
+		res = append(res, idx - 1)
+	}
+	if y > 0 {
+		res = append(res, idx - lay.cols)
+	}
+	if x < lay.cols - 1 {
+		res = append(res, idx + 1)
+	}
+	if y < lay.rows - 1 {
+		res = append(res, idx + lay.cols)
+	}
+	return res
+}
+
+// degree returns the number of open adjacent cells
+func (lay *layout) degree(idx int) int {
+	res := 0
+	for _, n := range lay.neighbours(idx) {
+		if lay.open[n] {
+			res++
+		}
+	}
+	return res
+}
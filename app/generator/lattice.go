@@ -0,0 +1,59 @@
+package generator
+
+// Lattice is a grid of rooms, generators carve passages between adjacent rooms
+type Lattice struct {
+	Width, Height int
+
+	// passages to the east and to the south neighbour of each room
+	east, south []bool
+}
+
+func newLattice(width, height int) *Lattice {
+	return &Lattice{
+		Width: width,
+		Height: height,
+		east: make([]bool, width * height),
+		south: make([]bool, width * height),
+	}
+}
+
+// Rooms returns the number of rooms
+func (l *Lattice) Rooms() int {
+	return l.Width * l.Height
+}
+
+// Index returns the room index by 0-based coordinates
+func (l *Lattice) Index(x, y int) int {
+	return y * l.Width + x
+}
+
+// Neighbours returns horizontally and vertically adjacent rooms
+func (l *Lattice) Neighbours(room int) []int {
+	res := make([]int, 0, 4)
+	x, y := room % l.Width, room / l.Width
+	if x > 0 {
+		res = append(res, room - 1)
+	}
+	if y > 0 {
+		res = append(res, room - l.Width)
+	}
+	if x < l.Width - 1 {
+		res = append(res, room + 1)
+	}
+	if y < l.Height - 1 {
+		res = append(res, room + l.Width)
+	}
+	return res
+}
+
+// Connect carves a passage between adjacent rooms
+func (l *Lattice) Connect(a, b int) {
+	if a > b {
+		a, b = b, a
+	}
+	if b - a == l.Width {
+		l.south[a] = true
+	} else {
+		l.east[a] = true
+	}
+}
@@ -26,17 +26,29 @@
 //           read: read access
 //           write: write access
 //         flow: accessCode
+//     api_key:
+//         type: apiKey
+//         in: header
+//         name: X-API-Key
 //
 // swagger:meta
 package app
 
 import (
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp"
 	"github.com/revel/revel"
 
 	rgorp "github.com/revel/modules/orm/gorp/app"
 
+	"github.com/mkulish/mazes/app/auth"
 	"github.com/mkulish/mazes/app/controllers"
+	"github.com/mkulish/mazes/app/generator"
+	"github.com/mkulish/mazes/app/migrations"
 	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/services"
 )
 
 var (
@@ -66,8 +78,17 @@ func init() {
 	}
 
 	revel.InterceptMethod(controllers.Maze.Auth, revel.BEFORE)
+	revel.InterceptMethod(controllers.User.Auth, revel.BEFORE)
+	revel.InterceptMethod(controllers.Org.Auth, revel.BEFORE)
+	revel.InterceptMethod(controllers.Admin.Auth, revel.BEFORE)
+	revel.InterceptMethod(controllers.Admin.RequireAdmin, revel.BEFORE)
 
 	revel.OnAppStart(InitSQLite)
+	revel.OnAppStart(InitMazeConfig)
+	revel.OnAppStart(InitMazeStorage)
+	revel.OnAppStart(InitJWTKeys)
+	revel.OnAppStart(InitLoginLimiter)
+	revel.OnAppStart(InitAdmins)
 }
 
 // HeaderFilter adds common security headers
@@ -82,18 +103,200 @@ var HeaderFilter = func(c *revel.Controller, fc []revel.Filter) {
 	fc[0](c, fc[1:]) // Execute the next filter stage.
 }
 
-// InitSQLite initializes sqlite schema
+// InitSQLite maps the models to sqlite tables and migrates the schema,
+// refuses to start on a newer or (if auto migration is disabled) outdated schema
 func InitSQLite() {
 	Dbm := rgorp.Db.Map
 
-	t := Dbm.AddTable(models.User{}).SetKeys(true, "ID")
-	t.AddIndex("UsernameIndex", "Btree", []string{"Username"}).SetUnique(true)
+	// the schema is owned by migrations, mapped tables are only checked against it
+	var tables []*gorp.TableMap
+	mapTable := func(model interface{}) *gorp.TableMap {
+		t := Dbm.AddTable(model)
+		tables = append(tables, t)
+		return t
+	}
+
+	t := mapTable(models.User{}).SetKeys(true, "ID")
 	t.ColMap("Password").Transient = true
 
-	t = Dbm.AddTable(models.Maze{}).SetKeys(true, "ID")
-	t.AddIndex("OwnerIDIndex", "Btree", []string{"OwnerID"})
+	t = mapTable(models.Maze{}).SetKeys(true, "ID")
 	t.ColMap("Walls").Transient = true
 
+	mapTable(models.MazeCell{}).SetKeys(false, "MazeID", "X", "Y")
+
+	t = mapTable(models.MazeShare{}).SetKeys(false, "MazeID", "UserID")
+	t.ColMap("Username").Transient = true
+
+	t = mapTable(models.Org{}).SetKeys(true, "ID")
+	t.ColMap("Role").Transient = true
+
+	t = mapTable(models.OrgMember{}).SetKeys(false, "OrgID", "UserID")
+	t.ColMap("Username").Transient = true
+
+	mapTable(models.RefreshToken{}).SetKeys(true, "ID")
+
+	mapTable(models.RevokedToken{}).SetKeys(false, "JTI")
+
+	mapTable(models.AccessToken{}).SetKeys(true, "ID")
+
+	mapTable(models.APIKey{}).SetKeys(true, "ID")
+
+	mapTable(models.LoginAttempt{}).SetKeys(false, "Subject")
+
+	t = mapTable(models.AuditEvent{}).SetKeys(true, "ID")
+	t.ColMap("Diff").Transient = true
+
 	rgorp.Db.TraceOn(revel.AppLog)
-	Dbm.CreateTables()
+
+	migrator, err := migrations.New(Dbm.Db)
+	if err != nil {
+		revel.AppLog.Fatalf("Schema migrations: %v", err)
+	}
+	if err := migrator.Check(); err != nil {
+		revel.AppLog.Fatalf("Schema migrations: %v", err)
+	}
+
+	if !revel.Config.BoolDefault("db.migrate", true) {
+		version, err := migrator.Version()
+		if err != nil {
+			revel.AppLog.Fatalf("Schema migrations: %v", err)
+		}
+		if version < migrator.Latest() {
+			revel.AppLog.Fatalf("Database schema version %d is outdated, apply migrations up to %d: go run ./cmd/mazes migrate up", version, migrator.Latest())
+		}
+		return
+	}
+
+	applied, err := migrator.Up(0)
+	for _, migration := range applied {
+		revel.AppLog.Infof("Schema migration %d_%s applied", migration.Version, migration.Name)
+	}
+	if err != nil {
+		revel.AppLog.Fatalf("Schema migrations: %v", err)
+	}
+	checkMappedColumns(migrator, tables)
+}
+
+// checkMappedColumns refuses to start if the migrated schema lacks columns of the mapped models
+func checkMappedColumns(migrator *migrations.Migrator, tables []*gorp.TableMap) {
+	for _, table := range tables {
+		columns, err := migrator.Columns(table.TableName)
+		if err != nil {
+			revel.AppLog.Fatalf("Schema check: %v", err)
+		}
+		existing := map[string]bool{}
+		for _, column := range columns {
+			existing[column] = true
+		}
+
+		var missing []string
+		for _, column := range table.Columns {
+			if !column.Transient && !existing[column.ColumnName] {
+				missing = append(missing, column.ColumnName)
+			}
+		}
+		if len(missing) > 0 {
+			revel.AppLog.Fatalf("Schema check: table %s lacks mapped columns %s, add a migration", table.TableName, strings.Join(missing, ", "))
+		}
+	}
+}
+
+// InitMazeConfig configures maze grid limits, solver search and generation budgets
+func InitMazeConfig() {
+	models.MaxGridRows = revel.Config.IntDefault("maze.grid.rows", models.MaxGridRows)
+	models.MaxGridCols = revel.Config.IntDefault("maze.grid.cols", models.MaxGridCols)
+
+	services.DefaultBudget = services.Budget{
+		Nodes:   revel.Config.IntDefault("maze.solver.nodes", services.DefaultBudget.Nodes),
+		Timeout: time.Duration(revel.Config.IntDefault("maze.solver.timeout", int(services.DefaultBudget.Timeout / time.Millisecond))) * time.Millisecond,
+	}
+
+	generator.DefaultBudget = generator.Budget{
+		Attempts: revel.Config.IntDefault("maze.generator.attempts", generator.DefaultBudget.Attempts),
+		Timeout:  time.Duration(revel.Config.IntDefault("maze.generator.timeout", int(generator.DefaultBudget.Timeout / time.Millisecond))) * time.Millisecond,
+	}
+}
+
+// InitMazeStorage configures maze walls storage and fills missing wall cells
+func InitMazeStorage() {
+	models.StoreWallCells = revel.Config.BoolDefault("maze.storage.cells", models.StoreWallCells)
+
+	txn, err := rgorp.Db.Map.Begin()
+	if err != nil {
+		revel.AppLog.Fatalf("maze wall cells backfill: %v", err)
+	}
+	filled, err := models.BackfillWallCells(txn)
+	if err != nil {
+		txn.Rollback()
+		revel.AppLog.Fatalf("maze wall cells backfill: %v", err)
+	}
+	if err := txn.Commit(); err != nil {
+		revel.AppLog.Fatalf("maze wall cells backfill: %v", err)
+	}
+	if filled > 0 {
+		revel.AppLog.Infof("maze wall cells backfill: %d mazes filled", filled)
+	}
+}
+
+// InitJWTKeys loads auth token signing keys and token lifetimes
+func InitJWTKeys() {
+	auth.AccessTokenTTL = time.Duration(revel.Config.IntDefault("auth.token.ttl", int(auth.AccessTokenTTL / time.Second))) * time.Second
+	auth.RefreshTokenTTL = time.Duration(revel.Config.IntDefault("auth.refresh.ttl", int(auth.RefreshTokenTTL / time.Second))) * time.Second
+	auth.PersonalTokenTTL = time.Duration(revel.Config.IntDefault("auth.pat.ttl", int(auth.PersonalTokenTTL / time.Second))) * time.Second
+
+	keyring, err := auth.LoadKeyring(revel.Config, revel.BasePath)
+	if err != nil {
+		revel.AppLog.Fatalf("JWT keys: %v", err)
+	}
+
+	for _, key := range keyring.Keys() {
+		if key.Ephemeral {
+			revel.AppLog.Warnf("JWT key %s secret is not configured, random secret is used until restart", key.ID)
+		}
+	}
+	auth.DefaultKeyring = keyring
+}
+
+// InitLoginLimiter configures login attempts throttling and the limiter state store
+func InitLoginLimiter() {
+	auth.UsernameThrottle = auth.Throttle{
+		Free:    revel.Config.IntDefault("auth.login.user.free", auth.UsernameThrottle.Free),
+		Lockout: revel.Config.IntDefault("auth.login.user.lockout", auth.UsernameThrottle.Lockout),
+	}
+	auth.IPThrottle = auth.Throttle{
+		Free:    revel.Config.IntDefault("auth.login.ip.free", auth.IPThrottle.Free),
+		Lockout: revel.Config.IntDefault("auth.login.ip.lockout", auth.IPThrottle.Lockout),
+	}
+
+	limiter := auth.DefaultLimiter
+	limiter.Delay = time.Duration(revel.Config.IntDefault("auth.login.delay", int(limiter.Delay / time.Millisecond))) * time.Millisecond
+	limiter.MaxDelay = time.Duration(revel.Config.IntDefault("auth.login.maxdelay", int(limiter.MaxDelay / time.Millisecond))) * time.Millisecond
+	limiter.LockoutTime = time.Duration(revel.Config.IntDefault("auth.login.lockouttime", int(limiter.LockoutTime / time.Millisecond))) * time.Millisecond
+
+	switch store := revel.Config.StringDefault("auth.login.limiter", "memory"); store {
+	case "memory":
+		limiter.Store = auth.NewMemoryStore(limiter.LockoutTime)
+	case "db":
+		limiter.Store = auth.NewDBStore(rgorp.Db.Map)
+	default:
+		revel.AppLog.Fatalf("Unknown login limiter store: %s", store)
+	}
+}
+
+// InitAdmins syncs the admin role of existing users with the configured usernames
+func InitAdmins() {
+	for _, username := range strings.Split(revel.Config.StringDefault("auth.admins", ""), ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			models.AdminUsernames[username] = true
+		}
+	}
+
+	// the role is never granted on registration, accounts are promoted by the config only
+	promoted, demoted, err := models.SyncAdminRoles(rgorp.Db.Map, models.AdminUsernames)
+	if err != nil {
+		revel.AppLog.Fatalf("Admin roles: %v", err)
+	}
+	if promoted > 0 || demoted > 0 {
+		revel.AppLog.Infof("Admin roles: %d users promoted, %d demoted", promoted, demoted)
+	}
 }
@@ -0,0 +1,318 @@
+// Package migrations applies versioned schema migrations embedded from sql/*.sql files.
+// Migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// versions start from 1 without gaps, applied versions are kept in schema_migrations table.
+// Data migrations which can't be expressed in SQL are Go steps of the versions (steps.go).
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration represents a schema version change
+type Migration struct {
+	Version int
+	Name    string
+	// Up and Down SQL scripts
+	Up   string
+	Down string
+	// optional data migration steps
+	UpStep   Step
+	DownStep Step
+}
+
+// All returns embedded migrations ordered by version
+func All() ([]*Migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("Unexpected migration file: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := files.ReadFile(path.Join("sql", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("Migration %d names mismatch: %s, %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i + 1 {
+			return nil, fmt.Errorf("Migration %d is missing", i + 1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("Migration %d should have both up and down scripts", m.Version)
+		}
+		m.UpStep, m.DownStep = steps[m.Version].Up, steps[m.Version].Down
+	}
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations of the database
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+// New returns migrator of the database with the embedded migrations
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest returns the latest known schema version
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version returns the current schema version of the database, 0 if empty.
+// Databases created by gorp before migrations were introduced are recorded as version 1
+func (m *Migrator) Version() (int, error) {
+	if err := m.init(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := m.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// Check returns error if the database schema is newer than the latest known version
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("Database schema version %d is newer than the app schema version %d", version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies migrations up to the target version (the latest if 0), returns applied migrations
+func (m *Migrator) Up(target int) ([]*Migration, error) {
+	if target == 0 {
+		target = m.Latest()
+	}
+	if target < 0 || target > m.Latest() {
+		return nil, fmt.Errorf("Unknown schema version %d", target)
+	}
+	if err := m.Check(); err != nil {
+		return nil, err
+	}
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []*Migration
+	for _, migration := range m.migrations[version:target] {
+		err := m.apply(migration.Up, nil, migration.UpStep,
+			"INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)",
+			migration.Version, migration.Name, time.Now().Unix())
+		if err != nil {
+			return applied, fmt.Errorf("Migration %d_%s up: %v", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down rolls back the latest applied migrations, returns rolled back migrations
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+	if err := m.Check(); err != nil {
+		return nil, err
+	}
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if steps < 0 || steps > version {
+		return nil, fmt.Errorf("Can't roll back %d migrations of schema version %d", steps, version)
+	}
+
+	var rolledBack []*Migration
+	for i := version; i > version - steps; i-- {
+		migration := m.migrations[i - 1]
+		err := m.apply(migration.Down, migration.DownStep, nil, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+		if err != nil {
+			return rolledBack, fmt.Errorf("Migration %d_%s down: %v", migration.Version, migration.Name, err)
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
+// apply executes migration script with the steps before and after it
+// and schema_migrations update in a transaction
+func (m *Migrator) apply(script string, before, after Step, statement string, args ...interface{}) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := m.execute(tx, script, before, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(statement, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// execute runs migration script with the optional steps
+func (m *Migrator) execute(tx *sql.Tx, script string, before, after Step) error {
+	if before != nil {
+		if err := before(tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if after != nil {
+		return after(tx)
+	}
+	return nil
+}
+
+// legacyTables are the tables and columns created by gorp before migrations were introduced,
+// the schema of migration 1
+var legacyTables = map[string]string{
+	"User": "ID,Username,HashedPassword",
+	"Maze": "ID,OwnerID,Entrance,GridSize,WallsStr,MinPathStr,MaxPathStr",
+}
+
+// Columns returns column names of the table in order, empty if the table doesn't exist
+func (m *Migrator) Columns(table string) ([]string, error) {
+	return queryColumns(m.db, table)
+}
+
+// queryColumns returns column names of the table in order
+func queryColumns(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, table string) ([]string, error) {
+	rows, err := q.Query("SELECT name FROM pragma_table_info(?) ORDER BY cid", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// init creates schema_migrations table, records existing legacy schema as version 1.
+// Unversioned schema other than the legacy one is an error, it can't be migrated safely
+func (m *Migrator) init() error {
+	var tables int
+	err := m.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&tables)
+	if err != nil || tables > 0 {
+		return err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := m.stampLegacy(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// stampLegacy creates schema_migrations table, records version 1 if the tables were created by gorp
+func (m *Migrator) stampLegacy(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("CREATE TABLE schema_migrations (version integer not null primary key, name varchar(255), applied integer)"); err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	for _, table := range tables {
+		columns, err := queryColumns(tx, table)
+		if err != nil {
+			return err
+		}
+		if legacyTables[table] != strings.Join(columns, ",") {
+			return fmt.Errorf("Unversioned database schema doesn't match migration %d_%s: table %s (%s)",
+				m.migrations[0].Version, m.migrations[0].Name, table, strings.Join(columns, ", "))
+		}
+	}
+	if len(tables) != len(legacyTables) {
+		return fmt.Errorf("Unversioned database schema doesn't match migration %d_%s: tables %s",
+			m.migrations[0].Version, m.migrations[0].Name, strings.Join(tables, ", "))
+	}
+
+	_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)",
+		m.migrations[0].Version, m.migrations[0].Name, time.Now().Unix())
+	return err
+}
@@ -0,0 +1,3 @@
+-- Initial schema, the tables created by gorp before migrations were introduced
+CREATE TABLE "User" ("ID" integer not null primary key autoincrement, "Username" varchar(255), "HashedPassword" blob);
+CREATE TABLE "Maze" ("ID" integer not null primary key autoincrement, "OwnerID" integer, "Entrance" varchar(255), "GridSize" varchar(255), "WallsStr" varchar(255), "MinPathStr" varchar(255), "MaxPathStr" varchar(255));
@@ -0,0 +1,4 @@
+-- Longest path optimality flag and exit policies, existing solutions are not known to be optimal
+ALTER TABLE "Maze" ADD COLUMN "MaxPathOptimal" integer default 0;
+ALTER TABLE "Maze" ADD COLUMN "Exit" varchar(255) default '';
+ALTER TABLE "Maze" ADD COLUMN "ExitPolicy" varchar(255) default '';
@@ -0,0 +1,4 @@
+CREATE TABLE "Maze_down" ("ID" integer not null primary key autoincrement, "OwnerID" integer, "Entrance" varchar(255), "GridSize" varchar(255), "WallsStr" varchar(255), "MinPathStr" varchar(255), "MaxPathStr" varchar(255), "MaxPathOptimal" integer default 0, "Exit" varchar(255) default '', "ExitPolicy" varchar(255) default '');
+INSERT INTO "Maze_down" SELECT "ID", "OwnerID", "Entrance", "GridSize", "WallsStr", "MinPathStr", "MaxPathStr", "MaxPathOptimal", "Exit", "ExitPolicy" FROM "Maze";
+DROP TABLE "Maze";
+ALTER TABLE "Maze_down" RENAME TO "Maze";
@@ -0,0 +1,15 @@
+-- Denormalized mazes search columns, existing mazes are counted as created now
+ALTER TABLE "Maze" ADD COLUMN "Created" integer default 0;
+ALTER TABLE "Maze" ADD COLUMN "Rows" integer default 0;
+ALTER TABLE "Maze" ADD COLUMN "Cols" integer default 0;
+ALTER TABLE "Maze" ADD COLUMN "WallsCount" integer default 0;
+ALTER TABLE "Maze" ADD COLUMN "MinPathLen" integer default 0;
+ALTER TABLE "Maze" ADD COLUMN "MaxPathLen" integer default 0;
+UPDATE "Maze" SET
+    "Created" = CAST(strftime('%s', 'now') AS integer),
+    "Rows" = CAST(substr("GridSize", 1, instr("GridSize", 'x') - 1) AS integer),
+    "Cols" = CAST(substr("GridSize", instr("GridSize", 'x') + 1) AS integer),
+    "WallsCount" = CASE WHEN COALESCE("WallsStr", '') = '' THEN 0
+        ELSE length("WallsStr") - length(replace("WallsStr", ',', '')) + 1 END,
+    "MinPathLen" = length(COALESCE("MinPathStr", '')) - length(replace(COALESCE("MinPathStr", ''), ',', '')),
+    "MaxPathLen" = length(COALESCE("MaxPathStr", '')) - length(replace(COALESCE("MaxPathStr", ''), ',', ''));
@@ -0,0 +1,9 @@
+DROP TABLE "LoginAttempt";
+DROP TABLE "APIKey";
+DROP TABLE "AccessToken";
+DROP TABLE "RevokedToken";
+DROP TABLE "RefreshToken";
+CREATE TABLE "User_down" ("ID" integer not null primary key autoincrement, "Username" varchar(255), "HashedPassword" blob);
+INSERT INTO "User_down" SELECT "ID", "Username", "HashedPassword" FROM "User";
+DROP TABLE "User";
+ALTER TABLE "User_down" RENAME TO "User";
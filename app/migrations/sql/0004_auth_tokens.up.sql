@@ -0,0 +1,10 @@
+-- Token versions, refresh and revoked tokens, personal access tokens, API keys and login attempts,
+-- existing users are counted as registered now
+ALTER TABLE "User" ADD COLUMN "TokenVersion" integer default 0;
+ALTER TABLE "User" ADD COLUMN "Created" integer default 0;
+UPDATE "User" SET "Created" = CAST(strftime('%s', 'now') AS integer);
+CREATE TABLE "RefreshToken" ("ID" integer not null primary key autoincrement, "UserID" integer, "Hash" varchar(255), "Family" varchar(255), "Used" integer, "ExpiresAt" integer, "Created" integer);
+CREATE TABLE "RevokedToken" ("JTI" varchar(255) not null primary key, "ExpiresAt" integer);
+CREATE TABLE "AccessToken" ("ID" integer not null primary key autoincrement, "UserID" integer, "JTI" varchar(255), "Name" varchar(255), "Scope" varchar(255), "ExpiresAt" integer, "Created" integer);
+CREATE TABLE "APIKey" ("ID" integer not null primary key autoincrement, "UserID" integer, "Hash" varchar(255), "Prefix" varchar(255), "Label" varchar(255), "Scope" varchar(255), "ExpiresAt" integer, "LastUsed" integer, "Created" integer);
+CREATE TABLE "LoginAttempt" ("Subject" varchar(255) not null primary key, "Failures" integer, "Last" integer);
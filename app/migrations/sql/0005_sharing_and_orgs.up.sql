@@ -0,0 +1,6 @@
+-- Maze visibility and share lists, organizations owning mazes
+ALTER TABLE "Maze" ADD COLUMN "Visibility" varchar(255) default 'private';
+ALTER TABLE "Maze" ADD COLUMN "OrgID" integer default 0;
+CREATE TABLE "MazeShare" ("MazeID" integer not null, "UserID" integer not null, "Permission" varchar(255), primary key ("MazeID", "UserID"));
+CREATE TABLE "Org" ("ID" integer not null primary key autoincrement, "Name" varchar(255), "Created" integer);
+CREATE TABLE "OrgMember" ("OrgID" integer not null, "UserID" integer not null, "Role" varchar(255), primary key ("OrgID", "UserID"));
@@ -0,0 +1,5 @@
+DROP TABLE "AuditEvent";
+CREATE TABLE "User_down" ("ID" integer not null primary key autoincrement, "Username" varchar(255), "HashedPassword" blob, "TokenVersion" integer default 0, "Created" integer default 0);
+INSERT INTO "User_down" SELECT "ID", "Username", "HashedPassword", "TokenVersion", "Created" FROM "User";
+DROP TABLE "User";
+ALTER TABLE "User_down" RENAME TO "User";
@@ -0,0 +1,4 @@
+-- User roles and disabled accounts, audit log
+ALTER TABLE "User" ADD COLUMN "Role" varchar(255) default 'user';
+ALTER TABLE "User" ADD COLUMN "Disabled" integer default 0;
+CREATE TABLE "AuditEvent" ("ID" integer not null primary key autoincrement, "Time" integer, "ActorID" integer, "Actor" varchar(255), "Action" varchar(255), "TargetType" varchar(255), "TargetID" integer, "IP" varchar(255), "DiffStr" varchar(255));
@@ -0,0 +1,5 @@
+DROP TABLE "MazeCell";
+CREATE TABLE "Maze_down" ("ID" integer not null primary key autoincrement, "OwnerID" integer, "Entrance" varchar(255), "GridSize" varchar(255), "WallsStr" varchar(255), "MinPathStr" varchar(255), "MaxPathStr" varchar(255), "MaxPathOptimal" integer default 0, "Exit" varchar(255) default '', "ExitPolicy" varchar(255) default '', "Created" integer default 0, "Rows" integer default 0, "Cols" integer default 0, "WallsCount" integer default 0, "MinPathLen" integer default 0, "MaxPathLen" integer default 0, "Visibility" varchar(255) default 'private', "OrgID" integer default 0);
+INSERT INTO "Maze_down" SELECT "ID", "OwnerID", "Entrance", "GridSize", "WallsStr", "MinPathStr", "MaxPathStr", "MaxPathOptimal", "Exit", "ExitPolicy", "Created", "Rows", "Cols", "WallsCount", "MinPathLen", "MaxPathLen", "Visibility", "OrgID" FROM "Maze";
+DROP TABLE "Maze";
+ALTER TABLE "Maze_down" RENAME TO "Maze";
@@ -0,0 +1,3 @@
+-- Walls bitset and normalized wall cells
+ALTER TABLE "Maze" ADD COLUMN "WallsBits" blob;
+CREATE TABLE "MazeCell" ("MazeID" integer not null, "X" integer not null, "Y" integer not null, primary key ("MazeID", "X", "Y"));
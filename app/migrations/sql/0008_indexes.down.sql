@@ -0,0 +1,14 @@
+DROP INDEX "AuditTargetIndex";
+DROP INDEX "AuditActorIDIndex";
+DROP INDEX "APIKeyHashIndex";
+DROP INDEX "APIKeyUserIDIndex";
+DROP INDEX "JTIIndex";
+DROP INDEX "UserIDIndex";
+DROP INDEX "FamilyIndex";
+DROP INDEX "HashIndex";
+DROP INDEX "OrgMemberUserIDIndex";
+DROP INDEX "OrgNameIndex";
+DROP INDEX "MazeShareUserIDIndex";
+DROP INDEX "OrgIDIndex";
+DROP INDEX "OwnerIDIndex";
+DROP INDEX "UsernameIndex";
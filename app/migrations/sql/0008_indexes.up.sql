@@ -0,0 +1,15 @@
+-- Lookup and uniqueness indexes, gorp never created the declared table indexes
+CREATE UNIQUE INDEX "UsernameIndex" ON "User" ("Username");
+CREATE INDEX "OwnerIDIndex" ON "Maze" ("OwnerID");
+CREATE INDEX "OrgIDIndex" ON "Maze" ("OrgID");
+CREATE INDEX "MazeShareUserIDIndex" ON "MazeShare" ("UserID");
+CREATE UNIQUE INDEX "OrgNameIndex" ON "Org" ("Name");
+CREATE INDEX "OrgMemberUserIDIndex" ON "OrgMember" ("UserID");
+CREATE UNIQUE INDEX "HashIndex" ON "RefreshToken" ("Hash");
+CREATE INDEX "FamilyIndex" ON "RefreshToken" ("Family");
+CREATE INDEX "UserIDIndex" ON "AccessToken" ("UserID");
+CREATE UNIQUE INDEX "JTIIndex" ON "AccessToken" ("JTI");
+CREATE INDEX "APIKeyUserIDIndex" ON "APIKey" ("UserID");
+CREATE UNIQUE INDEX "APIKeyHashIndex" ON "APIKey" ("Hash");
+CREATE INDEX "AuditActorIDIndex" ON "AuditEvent" ("ActorID");
+CREATE INDEX "AuditTargetIndex" ON "AuditEvent" ("TargetType", "TargetID");
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Step is a data migration executed in the migration transaction:
+// after the up script or before the down script
+type Step func(tx *sql.Tx) error
+
+// steps are data migrations of the versions which can't be expressed in SQL
+var steps = map[int]struct {
+	Up   Step
+	Down Step
+}{
+	7: {Up: encodeMazeWalls, Down: decodeMazeWalls},
+}
+
+// mazeWalls represents stored walls of a maze
+type mazeWalls struct {
+	id       int64
+	gridSize string
+	walls    []byte
+}
+
+// selectMazeWalls returns walls column of the mazes having it set
+func selectMazeWalls(tx *sql.Tx, column string) ([]mazeWalls, error) {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT "ID", "GridSize", "%s" FROM "Maze" WHERE length("%s") > 0`, column, column))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mazes []mazeWalls
+	for rows.Next() {
+		var maze mazeWalls
+		if err := rows.Scan(&maze.id, &maze.gridSize, &maze.walls); err != nil {
+			return nil, err
+		}
+		mazes = append(mazes, maze)
+	}
+	return mazes, rows.Err()
+}
+
+// encodeMazeWalls converts legacy comma-joined walls into bitsets
+func encodeMazeWalls(tx *sql.Tx) error {
+	mazes, err := selectMazeWalls(tx, "WallsStr")
+	if err != nil {
+		return err
+	}
+	for _, maze := range mazes {
+		rows, cols := models.ParseGridSize(maze.gridSize)
+		bits, err := models.EncodeWalls(strings.Split(string(maze.walls), ","), rows, cols)
+		if err != nil {
+			return fmt.Errorf("maze %d: %v", maze.id, err)
+		}
+		if _, err := tx.Exec(`UPDATE "Maze" SET "WallsBits" = ?, "WallsStr" = '' WHERE "ID" = ?`, bits, maze.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeMazeWalls converts walls bitsets back into comma-joined walls
+func decodeMazeWalls(tx *sql.Tx) error {
+	mazes, err := selectMazeWalls(tx, "WallsBits")
+	if err != nil {
+		return err
+	}
+	for _, maze := range mazes {
+		rows, cols := models.ParseGridSize(maze.gridSize)
+		walls := strings.Join(models.DecodeWalls(maze.walls, rows, cols), ",")
+		if _, err := tx.Exec(`UPDATE "Maze" SET "WallsStr" = ? WHERE "ID" = ?`, walls, maze.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
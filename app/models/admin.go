@@ -0,0 +1,120 @@
+package models
+
+import (
+	"github.com/revel/revel"
+)
+
+// AdminUser represents user data for administration
+// swagger:model AdminUser
+type AdminUser struct {
+	// User ID
+	// required: true
+	// type: integer
+	ID int64 `json:"id"`
+
+	// Username
+	// required: true
+	// example: mkulish
+	Username string `json:"username"`
+
+	// Role: user or admin
+	// required: true
+	// type: string
+	// enum: user,admin
+	// example: user
+	Role string `json:"role"`
+
+	// Disabled users can't login, their tokens and API keys are rejected
+	// required: true
+	// type: boolean
+	Disabled bool `json:"disabled"`
+
+	// Registration time (unix seconds)
+	// required: true
+	// type: integer
+	Created int64 `json:"created"`
+
+	// Number of user personal mazes
+	// required: true
+	// type: integer
+	Mazes int64 `json:"mazes"`
+}
+
+// AdminUserResponse represents a JSON response with user data for administration
+// swagger:model AdminUserResponse
+type AdminUserResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// User data
+	// required: true
+	Item *AdminUser `json:"item"`
+}
+
+// AdminUserListResponse represents a JSON response with users list
+// swagger:model AdminUserListResponse
+type AdminUserListResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Users list
+	// required: true
+	Items []*AdminUser `json:"items"`
+
+	// Cursor of the next page (user id), empty on the last page
+	// type: integer
+	NextCursor int64 `json:"nextCursor,omitempty"`
+}
+
+// AdminMaze represents maze data with the owner for administration
+// swagger:model AdminMaze
+type AdminMaze struct {
+	*Maze
+
+	// Owner (creator) user ID
+	// required: true
+	// type: integer
+	// example: 1
+	OwnerID int64 `json:"ownerId"`
+}
+
+// AdminMazeSearchResponse represents a JSON response with mazes list for administration
+// swagger:model AdminMazeSearchResponse
+type AdminMazeSearchResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Mazes list
+	// required: true
+	Items []*AdminMaze `json:"items"`
+
+	// Cursor of the next page, empty on the last page
+	// type: string
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// PasswordReset represents password reset data
+// swagger:model PasswordReset
+type PasswordReset struct {
+	// New password
+	// required: true
+	// min length: 5
+	// max length: 15
+	// example: test456!
+	NewPassword string `json:"newPassword"`
+}
+
+// Validate checks password reset data
+func (p *PasswordReset) Validate(v *revel.Validation) {
+	v.Check(p.NewPassword,
+		revel.Required{},
+		revel.MinSize{Min: 5},
+		revel.MaxSize{Max: 15},
+	).Key("newPassword")
+}
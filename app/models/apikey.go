@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/revel/revel"
+)
+
+// APIKey represents a personal API key for non-interactive clients, only the key hash is stored
+// swagger:model APIKey
+type APIKey struct {
+	// API key ID
+	// required: true
+	// type: integer
+	ID int64 `json:"id"`
+
+	// swagger:ignore
+	UserID int64 `json:"-"`
+
+	// swagger:ignore
+	Hash string `json:"-"`
+
+	// Beginning of the key to recognize it
+	// required: true
+	// example: mzk_3q2-7wE
+	Prefix string `json:"prefix"`
+
+	// Key label
+	// required: true
+	// example: CI
+	Label string `json:"label"`
+
+	// Space-delimited OAuth2 scopes
+	// required: true
+	// example: read
+	Scope string `json:"scope"`
+
+	// Expiration time (unix seconds), 0 - never expires
+	// required: true
+	// type: integer
+	ExpiresAt int64 `json:"expiresAt"`
+
+	// Last request time (unix seconds, minute precision), 0 - never used
+	// required: true
+	// type: integer
+	LastUsed int64 `json:"lastUsed"`
+
+	// Creation time (unix seconds)
+	// required: true
+	// type: integer
+	Created int64 `json:"created"`
+}
+
+// APIKeyRequest represents API key parameters
+// swagger:model APIKeyRequest
+type APIKeyRequest struct {
+	// Key label
+	// required: true
+	// max length: 50
+	// example: CI
+	Label string `json:"label"`
+
+	// Space-delimited OAuth2 scopes within the current scope (create only)
+	// example: read
+	Scope string `json:"scope,omitempty"`
+
+	// Key lifetime in seconds, never expires if not set (create only)
+	// type: integer
+	// example: 31536000
+	ExpiresIn int64 `json:"expiresIn,omitempty"`
+}
+
+// APIKeyResponse represents a JSON response with an API key
+// swagger:model APIKeyResponse
+type APIKeyResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// API key, returned on creation only
+	// type: string
+	Key string `json:"key,omitempty"`
+
+	// Key details
+	// required: true
+	Item *APIKey `json:"item"`
+}
+
+// APIKeyListResponse represents a JSON response with API keys
+// swagger:model APIKeyListResponse
+type APIKeyListResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// API keys
+	// required: true
+	Items []*APIKey `json:"items"`
+}
+
+// PreInsert hook is executed before inserting API key into sqlite
+func (k *APIKey) PreInsert(s gorp.SqlExecutor) error {
+	k.Created = time.Now().Unix()
+	return nil
+}
+
+// Validate checks API key parameters
+func (r *APIKeyRequest) Validate(v *revel.Validation) {
+	v.Check(r.Label,
+		revel.Required{},
+		revel.MaxSize{Max: 50},
+	).Key("label")
+	v.Min(int(r.ExpiresIn), 0).Key("expiresIn")
+}
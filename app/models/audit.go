@@ -0,0 +1,222 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/revel/revel"
+)
+
+// Audit actions
+const (
+	AuditRegister       = "user.register"
+	AuditLogin          = "user.login"
+	AuditLoginFailure   = "user.login.failure"
+	AuditLogout         = "user.logout"
+	AuditPasswordChange = "user.password.change"
+	AuditAccountDelete  = "user.delete"
+	AuditTokenRefresh   = "token.refresh"
+	AuditTokenReuse     = "token.reuse"
+	AuditTokenCreate    = "token.create"
+	AuditTokenDelete    = "token.delete"
+	AuditAPIKeyCreate   = "apikey.create"
+	AuditAPIKeyUpdate   = "apikey.update"
+	AuditAPIKeyDelete   = "apikey.delete"
+	AuditMazeCreate     = "maze.create"
+	AuditMazeUpdate     = "maze.update"
+	AuditMazeDelete     = "maze.delete"
+	AuditMazeShare      = "maze.share"
+	AuditMazeUnshare    = "maze.unshare"
+	AuditUserDisable    = "admin.user.disable"
+	AuditUserEnable     = "admin.user.enable"
+	AuditPasswordReset  = "admin.user.password.reset"
+)
+
+// Audit target types
+const (
+	AuditTargetUser   = "user"
+	AuditTargetToken  = "token"
+	AuditTargetAPIKey = "apikey"
+	AuditTargetMaze   = "maze"
+)
+
+var auditActionPattern = regexp.MustCompile("^[a-z.]+$")
+
+// AuditChange represents a changed field of the audit target
+// swagger:model AuditChange
+type AuditChange struct {
+	// Value before the change (not set on creation)
+	Old interface{} `json:"old,omitempty"`
+
+	// Value after the change (not set on deletion)
+	New interface{} `json:"new,omitempty"`
+}
+
+// AuditDiff represents changed fields of the audit target by JSON names
+type AuditDiff map[string]*AuditChange
+
+// NewAuditDiff returns changed JSON fields of the objects, nil old or new object means creation or deletion
+func NewAuditDiff(old, new interface{}) AuditDiff {
+	oldFields, newFields := jsonFields(old), jsonFields(new)
+
+	diff := AuditDiff{}
+	for name, value := range oldFields {
+		if newValue, found := newFields[name]; !found || !reflect.DeepEqual(value, newValue) {
+			diff[name] = &AuditChange{Old: value, New: newValue}
+		}
+	}
+	for name, value := range newFields {
+		if _, found := oldFields[name]; !found {
+			diff[name] = &AuditChange{New: value}
+		}
+	}
+	return diff
+}
+
+// jsonFields returns JSON object members of the value, nil if not an object
+func jsonFields(value interface{}) map[string]interface{} {
+	if v := reflect.ValueOf(value); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// AuditEvent represents a recorded security event or maze mutation
+// swagger:model AuditEvent
+type AuditEvent struct {
+	// Event ID
+	// required: true
+	// type: integer
+	ID int64 `json:"id"`
+
+	// Event time (unix seconds)
+	// required: true
+	// type: integer
+	// example: 1660000000
+	Time int64 `json:"time"`
+
+	// Actor user ID, 0 for anonymous actors (failed logins)
+	// required: true
+	// type: integer
+	// example: 1
+	ActorID int64 `json:"actorId"`
+
+	// Actor username (the attempted username for failed logins)
+	// required: true
+	// example: mkulish
+	Actor string `json:"actor"`
+
+	// Action
+	// required: true
+	// type: string
+	// example: maze.update
+	Action string `json:"action"`
+
+	// Target type
+	// required: true
+	// type: string
+	// enum: user,token,apikey,maze
+	// example: maze
+	TargetType string `json:"targetType"`
+
+	// Target ID, 0 if unknown
+	// required: true
+	// type: integer
+	// example: 1
+	TargetID int64 `json:"targetId"`
+
+	// Client IP
+	// required: true
+	// example: 127.0.0.1
+	IP string `json:"ip"`
+
+	// Changed fields of the target by JSON names
+	Diff AuditDiff `json:"diff,omitempty"`
+
+	// swagger:ignore
+	// diff stored as JSON in sqlite
+	DiffStr string `json:"-"`
+}
+
+// PostGet hook is executed after reading audit event from sqlite
+func (e *AuditEvent) PostGet(s gorp.SqlExecutor) error {
+	if e.DiffStr != "" && e.Diff == nil {
+		return json.Unmarshal([]byte(e.DiffStr), &e.Diff)
+	}
+	return nil
+}
+
+// PreInsert hook is executed before inserting audit event into sqlite
+func (e *AuditEvent) PreInsert(s gorp.SqlExecutor) error {
+	if len(e.Diff) > 0 {
+		data, err := json.Marshal(e.Diff)
+		if err != nil {
+			return err
+		}
+		e.DiffStr = string(data)
+	}
+	e.Time = time.Now().Unix()
+	return nil
+}
+
+// AuditQuery represents audit events filters and pagination, events are returned newest first
+type AuditQuery struct {
+	// Page size
+	Limit int
+	// Cursor of the next page (the last event ID of the previous page)
+	Cursor int64
+
+	// Actor user ID (admins only)
+	ActorID int64
+	// Action
+	Action string
+	// Target type and ID
+	TargetType string
+	TargetID   int64
+	// Events after time (RFC 3339) and its value parsed by Validate
+	Since     string
+	SinceTime time.Time
+}
+
+// Validate checks audit events query
+func (q *AuditQuery) Validate(v *revel.Validation) {
+	v.Range(q.Limit, 0, MaxSearchLimit).Key("limit")
+	v.Min(int(q.Cursor), 0).Key("cursor")
+
+	if q.Action != "" {
+		v.Check(q.Action, revel.ValidMatch(auditActionPattern)).Key("action")
+	}
+	if q.Since != "" {
+		since, err := time.Parse(time.RFC3339, q.Since)
+		if err != nil {
+			v.Error("Should be RFC 3339 time").Key("since")
+		}
+		q.SinceTime = since
+	}
+}
+
+// AuditListResponse represents a JSON response with audit events
+// swagger:model AuditListResponse
+type AuditListResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Events list, newest first
+	// required: true
+	Items []*AuditEvent `json:"items"`
+
+	// Cursor of the next page (event id), empty on the last page
+	// type: integer
+	NextCursor int64 `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-gorp/gorp"
+)
+
+// max column number parsed, prevents overflow on long letter sequences
+const maxColumn = 1 << 24
+
+// CellCoords parses spreadsheet-style cell notation: A2 -> x:0, y:1, AB10 -> x:27, y:9,
+// returns negative coordinates for incorrect cells (signed or zero-padded rows included)
+func CellCoords(rawCell string) (int, int) {
+	if !cellPattern.MatchString(rawCell) {
+		return -1, -1
+	}
+
+	x, i := 0, 0
+	for ; rawCell[i] >= 'A' && rawCell[i] <= 'Z'; i++ {
+		if x > maxColumn {
+			return -1, -1
+		}
+		// bijective base-26: A..Z, AA..ZZ, AAA...
+		x = x * 26 + int(rawCell[i] - 'A') + 1
+	}
+
+	y, err := strconv.Atoi(rawCell[i:])
+	if err != nil {
+		return -1, -1
+	}
+	// 0-based
+	return x - 1, y - 1
+}
+
+// CellName returns spreadsheet-style name of the cell by 0-based coordinates
+func CellName(x, y int) string {
+	return encodeColumn(x) + strconv.Itoa(y + 1)
+}
+
+// encodeColumn returns spreadsheet-style column letters: 0 -> A, 25 -> Z, 26 -> AA
+func encodeColumn(x int) string {
+	var res []byte
+	for x++; x > 0; x = (x - 1) / 26 {
+		res = append([]byte{byte('A' + (x - 1) % 26)}, res...)
+	}
+	return string(res)
+}
+
+// EncodeWalls returns compact walls bitset of the grid: one bit per cell in row-major order,
+// the least significant bit first
+func EncodeWalls(walls []string, rows, cols int) ([]byte, error) {
+	bits := make([]byte, (rows * cols + 7) / 8)
+	for _, rawCell := range walls {
+		x, y := CellCoords(rawCell)
+		if x < 0 || y < 0 || x >= cols || y >= rows {
+			return nil, fmt.Errorf("Wall is out of the grid: %s", rawCell)
+		}
+		idx := y * cols + x
+		bits[idx / 8] |= 1 << uint(idx % 8)
+	}
+	return bits, nil
+}
+
+// DecodeWalls returns wall cells of the bitset in row-major order
+func DecodeWalls(bits []byte, rows, cols int) []string {
+	var walls []string
+	for idx := 0; idx < rows * cols && idx / 8 < len(bits); idx++ {
+		if bits[idx / 8] & (1 << uint(idx % 8)) != 0 {
+			walls = append(walls, CellName(idx % cols, idx / cols))
+		}
+	}
+	return walls
+}
+
+// StoreWallCells enables normalized MazeCell rows of the maze walls (app.conf)
+var StoreWallCells = false
+
+// max MazeCell rows inserted by a statement, sqlite limits statement variables to 999
+const cellsInsertChunk = 300
+
+// MazeCell represents a wall cell of the maze, kept in sync with the walls bitset if enabled
+type MazeCell struct {
+	MazeID int64
+	// 0-based column and row
+	X int
+	Y int
+}
+
+// writeWallCells replaces MazeCell rows of the maze
+func writeWallCells(s gorp.SqlExecutor, m *Maze) error {
+	if _, err := s.Exec("DELETE FROM MazeCell WHERE MazeID = ?", m.ID); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(m.Walls); start += cellsInsertChunk {
+		end := start + cellsInsertChunk
+		if end > len(m.Walls) {
+			end = len(m.Walls)
+		}
+
+		values := make([]string, 0, end - start)
+		args := make([]interface{}, 0, 3 * (end - start))
+		for _, rawCell := range m.Walls[start:end] {
+			x, y := CellCoords(rawCell)
+			values = append(values, "(?, ?, ?)")
+			args = append(args, m.ID, x, y)
+		}
+		if _, err := s.Exec("INSERT INTO MazeCell (MazeID, X, Y) VALUES " + strings.Join(values, ", "), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillWallCells fills missing MazeCell rows if enabled, safe to run repeatedly
+func BackfillWallCells(s gorp.SqlExecutor) (int, error) {
+	if !StoreWallCells {
+		return 0, nil
+	}
+
+	var mazes []*Maze
+	if _, err := s.Select(&mazes, "SELECT * FROM Maze WHERE WallsCount > 0 AND ID NOT IN (SELECT DISTINCT MazeID FROM MazeCell)"); err != nil {
+		return 0, err
+	}
+	for _, maze := range mazes {
+		if err := writeWallCells(s, maze); err != nil {
+			return 0, fmt.Errorf("maze %d cells: %v", maze.ID, err)
+		}
+	}
+	return len(mazes), nil
+}
@@ -30,6 +30,50 @@ type UnauthorizedError struct {
 	Error string `json:"error"`
 }
 
+// ForbiddenError represents an insufficient token scope, resource permission or disabled account error
+// swagger:model ForbiddenError
+type ForbiddenError struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Forbidden error
+	// required: true
+	// type: string
+	Error string `json:"error"`
+
+	// Required OAuth2 scope (scope errors only)
+	// type: string
+	// example: write
+	Scope string `json:"scope,omitempty"`
+
+	// Required resource permission (permission errors only)
+	// type: string
+	// example: edit
+	Permission string `json:"permission,omitempty"`
+}
+
+// TooManyRequestsError represents a throttled request error
+// swagger:model TooManyRequestsError
+type TooManyRequestsError struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Too many requests error
+	// required: true
+	// type: string
+	Error string `json:"error"`
+
+	// Seconds until the next attempt is allowed (also in the Retry-After header)
+	// required: true
+	// type: integer
+	// example: 30
+	RetryAfter int64 `json:"retryAfter"`
+}
+
 // InternalError represents an unexpected internal error
 // swagger:model InternalError
 type InternalError struct {
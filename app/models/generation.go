@@ -0,0 +1,184 @@
+package models
+
+import "github.com/revel/revel"
+
+// MazeGeneration represents maze generation parameters
+// swagger:model MazeGeneration
+type MazeGeneration struct {
+	// Entrance cell on the grid, should be above the bottom row
+	// required: true
+	// type: string
+	// pattern: ^[A-Z]+[1-9][0-9]*$
+	// example: A1
+	Entrance string `json:"entrance"`
+
+	// Grid size (rows x cols, at least 2 rows and 2 columns)
+	// required: true
+	// pattern: ^[1-9][0-9]{0,5}x[1-9][0-9]{0,5}$
+	// example: 9x9
+	GridSize string `json:"gridSize"`
+
+	// Random seed, the same seed and parameters produce the same maze (random if not set)
+	// type: integer
+	// example: 42
+	Seed int64 `json:"seed"`
+
+	// Generation algorithm
+	// type: string
+	// enum: backtracker,binarytree,eller,kruskal,prim,sidewinder,wilson
+	// example: backtracker
+	Algorithm string `json:"algorithm"`
+
+	// Target maze metrics, mazes are regenerated and mutated until the metrics are within the ranges
+	Constraints *MazeConstraints `json:"constraints,omitempty"`
+
+	// Max generation attempts with constraints (up to the limit configured in app.conf)
+	// type: integer
+	// example: 100
+	Attempts int `json:"attempts,omitempty"`
+
+	// Owner organization ID (personal maze if not set)
+	// type: integer
+	// example: 1
+	OrgID int64 `json:"orgId,omitempty"`
+}
+
+// MazeConstraints represents generated maze metrics ranges, 0 upper bound means no limit
+// swagger:model MazeConstraints
+type MazeConstraints struct {
+	// Shortest path length range (steps)
+	// type: integer
+	// example: 60
+	MinPathFrom int `json:"minPathFrom,omitempty"`
+	// type: integer
+	MinPathTo int `json:"minPathTo,omitempty"`
+
+	// Longest path length range (steps)
+	// type: integer
+	MaxPathFrom int `json:"maxPathFrom,omitempty"`
+	// type: integer
+	MaxPathTo int `json:"maxPathTo,omitempty"`
+
+	// Dead ends number range
+	// type: integer
+	// example: 15
+	DeadEndsFrom int `json:"deadEndsFrom,omitempty"`
+	// type: integer
+	DeadEndsTo int `json:"deadEndsTo,omitempty"`
+
+	// Branching factor range (share of open cells with 3 or more open neighbours)
+	// type: number
+	BranchingFrom float64 `json:"branchingFrom,omitempty"`
+	// type: number
+	BranchingTo float64 `json:"branchingTo,omitempty"`
+}
+
+// MazeMetrics represents solver-measured maze difficulty metrics
+// swagger:model MazeMetrics
+type MazeMetrics struct {
+	// Shortest path length (steps)
+	// required: true
+	// type: integer
+	MinPath int `json:"minPath"`
+
+	// Longest path length (steps)
+	// required: true
+	// type: integer
+	MaxPath int `json:"maxPath"`
+
+	// Number of dead ends (open cells with a single open neighbour, entrance and exit excluded)
+	// required: true
+	// type: integer
+	DeadEnds int `json:"deadEnds"`
+
+	// Share of open cells with 3 or more open neighbours
+	// required: true
+	// type: number
+	Branching float64 `json:"branching"`
+}
+
+// MazeGenerationResponse represents a JSON reponse with generated maze id
+// swagger:model MazeGenerationResponse
+type MazeGenerationResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Maze ID
+	// required: true
+	// type: integer
+	ID int64 `json:"id"`
+
+	// Random seed used for generation
+	// required: true
+	// type: integer
+	Seed int64 `json:"seed"`
+
+	// Generation algorithm
+	// required: true
+	// type: string
+	Algorithm string `json:"algorithm"`
+
+	// Generated maze metrics (with constraints only)
+	Metrics *MazeMetrics `json:"metrics,omitempty"`
+
+	// Generation attempts used (with constraints only)
+	// type: integer
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// Validate checks maze generation parameters
+func (g *MazeGeneration) Validate(v *revel.Validation) {
+	v.Check(g.Entrance,
+		revel.Required{},
+		revel.ValidMatch(cellPattern),
+	).Key("entrance")
+
+	gridSize := v.Check(g.GridSize,
+		revel.Required{},
+		revel.ValidMatch(gridSizePattern),
+	).Key("gridSize")
+
+	if gridSize.Ok {
+		rows, cols := ParseGridSize(g.GridSize)
+		if rows > MaxGridRows || cols > MaxGridCols {
+			v.Error("Max grid size is %dx%d", MaxGridRows, MaxGridCols).Key("gridSize")
+		} else if rows < 2 {
+			v.Error("Grid should have at least 2 rows").Key("gridSize")
+		} else if cols < 2 {
+			// single column grids have no walls to carve
+			v.Error("Grid should have at least 2 columns").Key("gridSize")
+		}
+	}
+
+	v.Min(g.Attempts, 0).Key("attempts")
+	if g.Constraints != nil {
+		g.Constraints.Validate(v)
+	}
+}
+
+// Validate checks metrics ranges
+func (c *MazeConstraints) Validate(v *revel.Validation) {
+	ranges := []struct {
+		key      string
+		from, to int
+	}{
+		{"minPath", c.MinPathFrom, c.MinPathTo},
+		{"maxPath", c.MaxPathFrom, c.MaxPathTo},
+		{"deadEnds", c.DeadEndsFrom, c.DeadEndsTo},
+	}
+	for _, r := range ranges {
+		v.Min(r.from, 0).Key("constraints." + r.key + "From")
+		v.Min(r.to, 0).Key("constraints." + r.key + "To")
+		if r.to > 0 && r.from > r.to {
+			v.Error("Empty range: %d..%d", r.from, r.to).Key("constraints." + r.key)
+		}
+	}
+
+	v.RangeFloat(c.BranchingFrom, 0, 1).Key("constraints.branchingFrom")
+	v.RangeFloat(c.BranchingTo, 0, 1).Key("constraints.branchingTo")
+	if c.BranchingTo > 0 && c.BranchingFrom > c.BranchingTo {
+		v.Error("Empty range: %g..%g", c.BranchingFrom, c.BranchingTo).Key("constraints.branching")
+	}
+}
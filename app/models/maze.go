@@ -2,13 +2,46 @@ package models
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-gorp/gorp"
 	"github.com/revel/revel"
 
 )
 
+// Maze exit policies
+const (
+	ExitBottomEdge = "bottom-edge"
+	ExitAnyEdge    = "any-edge"
+	ExitCell       = "explicit-cell"
+	ExitNearest    = "nearest-of-many"
+)
+
+// Maze visibility
+const (
+	// VisibilityPrivate mazes are accessible by the owner only
+	VisibilityPrivate = "private"
+	// VisibilityShared mazes are accessible by the owner and the share list users
+	VisibilityShared = "shared"
+	// VisibilityPublic mazes are readable by all users, the share list users could edit them
+	VisibilityPublic = "public"
+)
+
+var (
+	// MaxGridRows is the max number of maze grid rows (app.conf)
+	MaxGridRows = 99
+	// MaxGridCols is the max number of maze grid columns (app.conf)
+	MaxGridCols = 27
+
+	cellPattern = regexp.MustCompile("^[A-Z]+[1-9][0-9]*$")
+	gridSizePattern = regexp.MustCompile("^[1-9][0-9]{0,5}x[1-9][0-9]{0,5}$")
+	exitPolicyPattern = regexp.MustCompile("^(bottom-edge|any-edge|explicit-cell|nearest-of-many)$")
+	visibilityPattern = regexp.MustCompile("^(private|shared|public)$")
+	permissionPattern = regexp.MustCompile("^(read|edit)$")
+)
+
 // Maze represents maze object with grid
 // swagger:model Maze
 type Maze struct {
@@ -18,27 +51,58 @@ type Maze struct {
 	// swagger:ignore
 	OwnerID int64 `json:"-"`
 
-	// Entrance cell on the grid
+	// Owner organization ID (personal maze if not set), set on creation only,
+	// the org members access the maze according to their roles
+	// type: integer
+	// example: 1
+	OrgID int64 `json:"orgId,omitempty"`
+
+	// Entrance cell on the grid (spreadsheet-style column letters and row number)
 	// required: true
 	// type: string
-	// pattern: ^[A-Z][1-9][0-9]?$
+	// pattern: ^[A-Z]+[1-9][0-9]*$
 	// example: A1
 	Entrance string `json:"entrance"`
 
-	// Grid size (cols x rows, up to 27x99)
+	// Grid size (rows x cols, up to 99x27 by default, max size is configured in app.conf)
 	// required: true
-	// pattern: ^([1-9]|1[0-9]|2[0-7])x[1-9][0-9]?$
+	// pattern: ^[1-9][0-9]{0,5}x[1-9][0-9]{0,5}$
 	// example: 4x3
 	GridSize string `json:"gridSize"`
 
-	// Array of wall cells
+	// Array of wall cells, returned in row-major order
 	// required: true
 	// example: ["B2", "B4", "C4"]
-	// items.pattern: ^[A-Z][1-9][0-9]?$
+	// items.pattern: ^[A-Z]+[1-9][0-9]*$
 	Walls []string `json:"walls"`
 
+	// Exit cell, used with explicit-cell exit policy only
+	// type: string
+	// pattern: ^[A-Z]+[1-9][0-9]*$
+	// example: C4
+	Exit string `json:"exit,omitempty"`
+
+	// Exit policy: bottom-edge (default, single exit on the bottom row),
+	// any-edge (single exit on any grid edge), explicit-cell (exit cell is set explicitly),
+	// nearest-of-many (multiple exits on any grid edge are allowed)
+	// type: string
+	// enum: bottom-edge,any-edge,explicit-cell,nearest-of-many
+	// example: any-edge
+	ExitPolicy string `json:"exitPolicy,omitempty"`
+
+	// Visibility: private (default, owner only), shared (owner and the share list users),
+	// public (readable by all users, the share list users could edit).
+	// Could be changed by the owner only, kept on update if not set
+	// type: string
+	// enum: private,shared,public
+	// example: shared
+	Visibility string `json:"visibility,omitempty"`
+
+	// swagger:ignore
+	// walls bitset, one bit per cell in row-major order
+	WallsBits []byte `json:"-"`
 	// swagger:ignore
-	// temporary fix for storing slice in sqlite
+	// legacy comma-joined walls, converted to bitset by schema migration 7, kept empty
 	WallsStr string `json:"-"`
 
 	// precalculated solutions
@@ -46,23 +110,125 @@ type Maze struct {
 	MinPathStr string `json:"-"`
 	// swagger:ignore
 	MaxPathStr string `json:"-"`
+	// max path search finished within the budget
+	// swagger:ignore
+	MaxPathOptimal bool `json:"-"`
+
+	// Creation time (unix timestamp)
+	// type: integer
+	// read only: true
+	// example: 1660000000
+	Created int64 `json:"created"`
+
+	// denormalized stats for mazes search
+	// swagger:ignore
+	Rows int `json:"-"`
+	// swagger:ignore
+	Cols int `json:"-"`
+	// swagger:ignore
+	WallsCount int `json:"-"`
+	// swagger:ignore
+	MinPathLen int `json:"-"`
+	// swagger:ignore
+	MaxPathLen int `json:"-"`
 }
 // PostGet hook is executed after reading maze from sqlite
 func (m *Maze) PostGet(s gorp.SqlExecutor) error {
-	if m.WallsStr != "" && len(m.Walls) == 0 {
-		// read walls slice from wallsStr column
-		m.Walls = strings.Split(m.WallsStr, ",")
+	if len(m.Walls) > 0 {
+		return nil
 	}
+	rows, cols := ParseGridSize(m.GridSize)
+	m.Walls = DecodeWalls(m.WallsBits, rows, cols)
 	return nil
 }
 // PreInsert hook is executed before inserting maze into sqlite
 func (m *Maze) PreInsert(s gorp.SqlExecutor) error {
-	if m.WallsStr == "" && len(m.Walls) > 0 {
-		// store walls slice in wallsStr column
-		m.WallsStr = strings.Join(m.Walls, ",")
+	if err := m.encodeWalls(); err != nil {
+		return err
+	}
+	if m.Visibility == "" {
+		m.Visibility = VisibilityPrivate
+	}
+	m.Created = time.Now().Unix()
+	m.updateStats()
+	return nil
+}
+// PostInsert hook is executed after inserting maze into sqlite
+func (m *Maze) PostInsert(s gorp.SqlExecutor) error {
+	if StoreWallCells {
+		return writeWallCells(s, m)
 	}
 	return nil
 }
+// PreUpdate hook is executed before updating maze in sqlite
+func (m *Maze) PreUpdate(s gorp.SqlExecutor) error {
+	// walls slice could be changed
+	if err := m.encodeWalls(); err != nil {
+		return err
+	}
+	if m.Visibility == "" {
+		m.Visibility = VisibilityPrivate
+	}
+	m.updateStats()
+	return nil
+}
+// PostUpdate hook is executed after updating maze in sqlite
+func (m *Maze) PostUpdate(s gorp.SqlExecutor) error {
+	if StoreWallCells {
+		return writeWallCells(s, m)
+	}
+	return nil
+}
+// PreDelete hook is executed before deleting maze from sqlite
+func (m *Maze) PreDelete(s gorp.SqlExecutor) error {
+	// cells could be left from the previous runs with the option enabled
+	_, err := s.Exec("DELETE FROM MazeCell WHERE MazeID = ?", m.ID)
+	return err
+}
+
+// encodeWalls stores walls slice in the bitset column, the legacy column is cleared
+func (m *Maze) encodeWalls() error {
+	rows, cols := ParseGridSize(m.GridSize)
+	bits, err := EncodeWalls(m.Walls, rows, cols)
+	if err != nil {
+		return err
+	}
+	m.WallsBits, m.WallsStr = bits, ""
+	return nil
+}
+
+// updateStats sets denormalized columns used by mazes search
+func (m *Maze) updateStats() {
+	m.Rows, m.Cols = ParseGridSize(m.GridSize)
+	m.WallsCount = len(m.Walls)
+
+	m.MinPathLen, m.MaxPathLen = 0, 0
+	if m.MinPathStr != "" {
+		m.MinPathLen = strings.Count(m.MinPathStr, ",")
+	}
+	if m.MaxPathStr != "" {
+		m.MaxPathLen = strings.Count(m.MaxPathStr, ",")
+	}
+}
+
+// ParseGridSize returns rows and cols of the grid size (rows x cols), zeros if incorrect
+func ParseGridSize(gridSize string) (int, int) {
+	size := strings.Split(gridSize, "x")
+	if len(size) != 2 {
+		return 0, 0
+	}
+	rows, _ := strconv.Atoi(size[0])
+	cols, _ := strconv.Atoi(size[1])
+	return rows, cols
+}
+
+// Density returns walls density (walls / cells)
+func (m *Maze) Density() float64 {
+	if m.Rows * m.Cols == 0 {
+		return 0
+	}
+	return float64(m.WallsCount) / float64(m.Rows * m.Cols)
+}
 
 // MazeResponse represents a JSON reponse with created maze id
 // swagger:model MazeResponse
@@ -78,6 +244,55 @@ type MazeResponse struct {
 	ID int64 `json:"id"`
 }
 
+// MazeItemResponse represents a JSON reponse with maze data
+// swagger:model MazeItemResponse
+type MazeItemResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Maze data
+	// required: true
+	Item *Maze `json:"item"`
+}
+
+// MazePatch represents JSON Merge Patch (RFC 7386) of the maze, members set to null are removed
+// swagger:model MazePatch
+type MazePatch struct {
+	// Entrance cell on the grid
+	// type: string
+	// example: A1
+	Entrance string `json:"entrance,omitempty"`
+
+	// Grid size (rows x cols)
+	// type: string
+	// example: 4x3
+	GridSize string `json:"gridSize,omitempty"`
+
+	// Array of wall cells replacing existing ones
+	// example: ["B2", "B4", "C4"]
+	Walls []string `json:"walls,omitempty"`
+
+	// Exit cell
+	// type: string
+	// example: C4
+	Exit string `json:"exit,omitempty"`
+
+	// Exit policy
+	// type: string
+	// example: any-edge
+	ExitPolicy string `json:"exitPolicy,omitempty"`
+
+	// Wall cells to add
+	// example: ["C3"]
+	AddWalls []string `json:"addWalls,omitempty"`
+
+	// Wall cells to remove
+	// example: ["B4"]
+	RemoveWalls []string `json:"removeWalls,omitempty"`
+}
+
 // MazeSolutionResponse represents a JSON reponse with maze solution path
 // swagger:model MazeSolutionResponse
 type MazeSolutionResponse struct {
@@ -89,6 +304,20 @@ type MazeSolutionResponse struct {
 	// Cells path
 	// required: true
 	Path []string `json:"path"`
+
+	// Path is proven optimal, false means the best path found so far within the search budget
+	// required: true
+	// type: boolean
+	Optimal bool `json:"optimal"`
+
+	// Algorithm used to solve the maze on request
+	// type: string
+	// example: astar
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// Solving time in milliseconds (on request only)
+	// type: number
+	Elapsed float64 `json:"elapsed,omitempty"`
 }
 
 // MazeSearchResponse represents a JSON reponse with mazes list
@@ -103,6 +332,10 @@ type MazeSearchResponse struct {
 	// required: true
 	// type: array
 	Items []*Maze `json:"items"`
+
+	// Cursor of the next page, empty on the last page
+	// type: string
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // Validate checks maze data
@@ -111,12 +344,39 @@ func (m *Maze) Validate(v *revel.Validation) {
 
 	v.Check(m.Entrance,
 		revel.Required{},
-		revel.ValidMatch(regexp.MustCompile("^[A-Z][1-9][0-9]?$")),
+		revel.ValidMatch(cellPattern),
 	).Key("entrance")
 
-	v.Check(m.GridSize,
+	gridSize := v.Check(m.GridSize,
 		revel.Required{},
-		// max size is 99x27
-		revel.ValidMatch(regexp.MustCompile("^[1-9][0-9]?x([1-9]|1[0-9]|2[0-7])$")),
+		revel.ValidMatch(gridSizePattern),
 	).Key("gridSize")
+
+	if gridSize.Ok {
+		rows, cols := ParseGridSize(m.GridSize)
+		if rows > MaxGridRows || cols > MaxGridCols {
+			v.Error("Max grid size is %dx%d", MaxGridRows, MaxGridCols).Key("gridSize")
+		}
+	}
+
+	if m.ExitPolicy != "" {
+		v.Check(m.ExitPolicy,
+			revel.ValidMatch(exitPolicyPattern),
+		).Key("exitPolicy").Message("Should be one of: bottom-edge, any-edge, explicit-cell, nearest-of-many")
+	}
+
+	if m.Visibility != "" {
+		v.Check(m.Visibility,
+			revel.ValidMatch(visibilityPattern),
+		).Key("visibility").Message("Should be one of: private, shared, public")
+	}
+
+	if m.ExitPolicy == ExitCell {
+		v.Check(m.Exit,
+			revel.Required{},
+			revel.ValidMatch(cellPattern),
+		).Key("exit")
+	} else if m.Exit != "" {
+		v.Error("Exit cell is only allowed with explicit-cell exit policy").Key("exit")
+	}
 }
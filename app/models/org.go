@@ -0,0 +1,158 @@
+package models
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/revel/revel"
+)
+
+// Organization membership roles
+const (
+	// OrgRoleOwner members have full access to the org mazes and manage the org members
+	OrgRoleOwner = "owner"
+	// OrgRoleEditor members create and edit the org mazes
+	OrgRoleEditor = "editor"
+	// OrgRoleViewer members read the org mazes
+	OrgRoleViewer = "viewer"
+)
+
+var orgRolePattern = regexp.MustCompile("^(owner|editor|viewer)$")
+
+// Org represents an organization owning a shared mazes library
+// swagger:model Org
+type Org struct {
+	// Organization ID
+	// required: true
+	// type: integer
+	// example: 1
+	ID int64 `json:"id"`
+
+	// Organization name
+	// required: true
+	// example: mazes-team
+	Name string `json:"name"`
+
+	// Role of the user in the organization
+	// required: true
+	// type: string
+	// enum: owner,editor,viewer
+	// example: owner
+	Role string `json:"role"`
+
+	// Creation time (unix timestamp)
+	// required: true
+	// type: integer
+	// example: 1660000000
+	Created int64 `json:"created"`
+}
+
+// PreInsert hook is executed before inserting organization into sqlite
+func (o *Org) PreInsert(s gorp.SqlExecutor) error {
+	o.Created = time.Now().Unix()
+	return nil
+}
+
+// OrgMember represents organization membership
+// swagger:model OrgMember
+type OrgMember struct {
+	// swagger:ignore
+	OrgID int64 `json:"-"`
+
+	// User ID
+	// required: true
+	// type: integer
+	// example: 2
+	UserID int64 `json:"userId"`
+
+	// Username
+	// required: true
+	// example: mkulish
+	Username string `json:"username"`
+
+	// Role: owner (full access, members management), editor (create and edit mazes), viewer (read mazes)
+	// required: true
+	// type: string
+	// enum: owner,editor,viewer
+	// example: editor
+	Role string `json:"role"`
+}
+
+// OrgRequest represents organization creation data
+// swagger:model OrgRequest
+type OrgRequest struct {
+	// Organization name, unique
+	// required: true
+	// min length: 4
+	// max length: 30
+	// example: mazes-team
+	Name string `json:"name"`
+}
+
+// OrgMemberRequest represents organization member role
+// swagger:model OrgMemberRequest
+type OrgMemberRequest struct {
+	// Role: owner, editor or viewer
+	// required: true
+	// type: string
+	// enum: owner,editor,viewer
+	// example: editor
+	Role string `json:"role"`
+}
+
+// OrgResponse represents a JSON response with organization data
+// swagger:model OrgResponse
+type OrgResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Organization data
+	// required: true
+	Item *Org `json:"item"`
+}
+
+// OrgListResponse represents a JSON response with the user organizations
+// swagger:model OrgListResponse
+type OrgListResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Organizations list
+	// required: true
+	Items []*Org `json:"items"`
+}
+
+// OrgMemberListResponse represents a JSON response with the organization members
+// swagger:model OrgMemberListResponse
+type OrgMemberListResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Members list
+	// required: true
+	Items []*OrgMember `json:"items"`
+}
+
+// Validate checks organization data
+func (r *OrgRequest) Validate(v *revel.Validation) {
+	v.Check(r.Name,
+		revel.Required{},
+		revel.MinSize{Min: 4},
+		revel.MaxSize{Max: 30},
+	).Key("name")
+}
+
+// Validate checks organization member role
+func (r *OrgMemberRequest) Validate(v *revel.Validation) {
+	v.Check(r.Role,
+		revel.Required{},
+		revel.ValidMatch(orgRolePattern),
+	).Key("role").Message("Should be one of: owner, editor, viewer")
+}
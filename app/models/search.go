@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/revel/revel"
+)
+
+// Mazes search page size limits
+const (
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 500
+)
+
+// MazeSortKeys are allowed mazes search sort keys, "-" prefix means descending order
+var MazeSortKeys = []string{"id", "created", "size", "density", "minPath", "maxPath"}
+
+// MazeQuery represents mazes search filters, sorting and pagination
+type MazeQuery struct {
+	// Page size
+	Limit int
+	// Opaque cursor of the next page (from the previous page response)
+	Cursor string
+	// Sort key, "-" prefix means descending order
+	Sort string
+
+	// Grid size range (rows x cols), both dimensions are checked
+	MinSize, MaxSize string
+	// Walls density range (walls / cells), 0 max - no limit
+	MinDensity, MaxDensity float64
+	// Filters mazes with or without exact solutions ("true" or "false"). Every stored maze has
+	// a min path (unsolvable mazes are rejected), the longest path is a best found one
+	// if the solver budget was exhausted
+	HasSolution string
+	// Min path length range (steps), 0 max - no limit
+	MinPathFrom, MinPathTo int
+	// Created after time (RFC 3339) and its value parsed by Validate
+	CreatedAfter     string
+	CreatedAfterTime time.Time
+}
+
+// MazeCursor represents the position of the last item of the page in the sort order
+type MazeCursor struct {
+	Sort  string  `json:"s"`
+	Value float64 `json:"v"`
+	ID    int64   `json:"id"`
+}
+
+// Validate checks mazes search query
+func (q *MazeQuery) Validate(v *revel.Validation) {
+	v.Range(q.Limit, 0, MaxSearchLimit).Key("limit")
+
+	sortKey := strings.TrimPrefix(q.Sort, "-")
+	if q.Sort != "" {
+		found := false
+		for _, key := range MazeSortKeys {
+			found = found || key == sortKey
+		}
+		if !found {
+			v.Error("Should be one of: %s", strings.Join(MazeSortKeys, ", ")).Key("sort")
+		}
+	}
+
+	if q.MinSize != "" {
+		v.Check(q.MinSize, revel.ValidMatch(gridSizePattern)).Key("minSize")
+	}
+	if q.MaxSize != "" {
+		v.Check(q.MaxSize, revel.ValidMatch(gridSizePattern)).Key("maxSize")
+	}
+	v.RangeFloat(q.MinDensity, 0, 1).Key("minDensity")
+	v.RangeFloat(q.MaxDensity, 0, 1).Key("maxDensity")
+	if q.HasSolution != "" && q.HasSolution != "true" && q.HasSolution != "false" {
+		v.Error("Should be one of: true, false").Key("hasSolution")
+	}
+	v.Min(q.MinPathFrom, 0).Key("minPathFrom")
+	v.Min(q.MinPathTo, 0).Key("minPathTo")
+	if q.CreatedAfter != "" {
+		createdAfter, err := time.Parse(time.RFC3339, q.CreatedAfter)
+		if err != nil {
+			v.Error("Should be RFC 3339 time").Key("createdAfter")
+		}
+		q.CreatedAfterTime = createdAfter
+	}
+
+	if q.Cursor != "" {
+		if cursor, err := DecodeMazeCursor(q.Cursor); err != nil {
+			v.Error("Incorrect cursor").Key("cursor")
+		} else if cursor.Sort != q.Sort {
+			v.Error("Cursor doesn't match sort order").Key("cursor")
+		}
+	}
+}
+
+// Encode returns opaque cursor string
+func (c MazeCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeMazeCursor parses opaque cursor string
+func DecodeMazeCursor(cursor string) (*MazeCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &MazeCursor{}
+	if err = json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import "github.com/revel/revel"
+
+// Maze share permissions
+const (
+	PermissionRead = "read"
+	PermissionEdit = "edit"
+)
+
+// MazeShare represents maze access granted to a user, effective for shared and public mazes
+// swagger:model MazeShare
+type MazeShare struct {
+	// swagger:ignore
+	MazeID int64 `json:"-"`
+
+	// User ID
+	// required: true
+	// type: integer
+	// example: 2
+	UserID int64 `json:"userId"`
+
+	// Username
+	// required: true
+	// example: mkulish
+	Username string `json:"username"`
+
+	// Permission: read or edit (update and patch)
+	// required: true
+	// type: string
+	// enum: read,edit
+	// example: read
+	Permission string `json:"permission"`
+}
+
+// MazeShareRequest represents maze share permission
+// swagger:model MazeShareRequest
+type MazeShareRequest struct {
+	// Permission: read or edit (update and patch)
+	// required: true
+	// type: string
+	// enum: read,edit
+	// example: edit
+	Permission string `json:"permission"`
+}
+
+// MazeShareListResponse represents a JSON response with the maze share list
+// swagger:model MazeShareListResponse
+type MazeShareListResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Maze visibility, the share list is effective for shared and public mazes
+	// required: true
+	// type: string
+	Visibility string `json:"visibility"`
+
+	// Users with access
+	// required: true
+	Items []*MazeShare `json:"items"`
+}
+
+// Validate checks maze share permission
+func (r *MazeShareRequest) Validate(v *revel.Validation) {
+	v.Check(r.Permission,
+		revel.Required{},
+		revel.ValidMatch(permissionPattern),
+	).Key("permission").Message("Should be one of: read, edit")
+}
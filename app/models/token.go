@@ -0,0 +1,161 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-gorp/gorp"
+	"github.com/revel/revel"
+)
+
+// RefreshToken represents a server-side refresh token, only the token hash is stored.
+// Tokens are rotated on each refresh, tokens issued by the same login share the family.
+type RefreshToken struct {
+	ID     int64
+	UserID int64
+	// Hash is the hex SHA-256 of the token
+	Hash string
+	// Family is the random login session ID, reuse of a rotated token revokes the family
+	Family string
+	// Used is set once the token is rotated or revoked
+	Used      bool
+	ExpiresAt int64
+	Created   int64
+}
+
+// RevokedToken represents a revoked access token kept until it expires
+type RevokedToken struct {
+	// JTI is the access token ID
+	JTI       string
+	ExpiresAt int64
+}
+
+// AccessToken represents a personal access token, the token itself is returned on creation only
+// swagger:model AccessToken
+type AccessToken struct {
+	// Token ID
+	// required: true
+	// type: integer
+	ID int64 `json:"id"`
+
+	// swagger:ignore
+	UserID int64 `json:"-"`
+
+	// swagger:ignore
+	JTI string `json:"-"`
+
+	// Token name
+	// required: true
+	// example: CI dashboard
+	Name string `json:"name"`
+
+	// Space-delimited OAuth2 scopes
+	// required: true
+	// example: read
+	Scope string `json:"scope"`
+
+	// Expiration time (unix seconds)
+	// required: true
+	// type: integer
+	ExpiresAt int64 `json:"expiresAt"`
+
+	// Creation time (unix seconds)
+	// required: true
+	// type: integer
+	Created int64 `json:"created"`
+}
+
+// AccessTokenRequest represents personal access token parameters
+// swagger:model AccessTokenRequest
+type AccessTokenRequest struct {
+	// Token name
+	// required: true
+	// max length: 50
+	// example: CI dashboard
+	Name string `json:"name"`
+
+	// Space-delimited OAuth2 scopes within the scopes of the auth token
+	// required: true
+	// example: read
+	Scope string `json:"scope"`
+
+	// Token lifetime in seconds (up to the limit configured in app.conf, the limit if not set)
+	// type: integer
+	// example: 2592000
+	ExpiresIn int64 `json:"expiresIn,omitempty"`
+}
+
+// AccessTokenResponse represents a JSON response with a new personal access token
+// swagger:model AccessTokenResponse
+type AccessTokenResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Auth token
+	// required: true
+	// type: string
+	Token string `json:"token"`
+
+	// Token details
+	// required: true
+	Item *AccessToken `json:"item"`
+}
+
+// AccessTokenListResponse represents a JSON response with personal access tokens
+// swagger:model AccessTokenListResponse
+type AccessTokenListResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Active tokens
+	// required: true
+	Items []*AccessToken `json:"items"`
+}
+
+// TokenRefresh represents a refresh token request
+// swagger:model TokenRefresh
+type TokenRefresh struct {
+	// Refresh token
+	// required: true
+	// type: string
+	RefreshToken string `json:"refreshToken"`
+}
+
+// LogoutResponse represents logout response JSON
+// swagger:model LogoutResponse
+type LogoutResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+}
+
+// PreInsert hook is executed before inserting refresh token into sqlite
+func (t *RefreshToken) PreInsert(s gorp.SqlExecutor) error {
+	t.Created = time.Now().Unix()
+	return nil
+}
+
+// PreInsert hook is executed before inserting personal access token into sqlite
+func (t *AccessToken) PreInsert(s gorp.SqlExecutor) error {
+	t.Created = time.Now().Unix()
+	return nil
+}
+
+// Validate checks personal access token parameters
+func (r *AccessTokenRequest) Validate(v *revel.Validation) {
+	v.Check(r.Name,
+		revel.Required{},
+		revel.MaxSize{Max: 50},
+	).Key("name")
+	v.Required(r.Scope).Key("scope")
+	v.Min(int(r.ExpiresIn), 0).Key("expiresIn")
+}
+
+// Validate checks refresh token request
+func (r *TokenRefresh) Validate(v *revel.Validation) {
+	v.Required(r.RefreshToken).Key("refreshToken")
+}
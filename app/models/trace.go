@@ -0,0 +1,50 @@
+package models
+
+// MazeTraceEvent represents a solver search step
+// swagger:model MazeTraceEvent
+type MazeTraceEvent struct {
+	// Event kind: push (cell is added to the search frontier), pop (cell is taken from the frontier)
+	// required: true
+	// type: string
+	// enum: push,pop
+	Kind string `json:"kind"`
+
+	// Cell on the grid
+	// required: true
+	// type: string
+	// example: B2
+	Cell string `json:"cell"`
+
+	// Path steps from the entrance to the cell
+	// required: true
+	// type: integer
+	Steps int `json:"steps"`
+}
+
+// MazeTraceResponse represents a JSON reponse with solver search frames
+// swagger:model MazeTraceResponse
+type MazeTraceResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// Algorithm of the traced solver
+	// required: true
+	// type: string
+	// example: astar
+	Algorithm string `json:"algorithm"`
+
+	// Solution path
+	// required: true
+	Path []string `json:"path"`
+
+	// Search events in order
+	// required: true
+	Frames []MazeTraceEvent `json:"frames"`
+
+	// Events over the limit were dropped
+	// required: true
+	// type: boolean
+	Truncated bool `json:"truncated"`
+}
@@ -1,9 +1,22 @@
 package models
 
 import (
+	"strings"
+	"time"
+
+	"github.com/go-gorp/gorp"
 	"github.com/revel/revel"
 )
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// AdminUsernames are granted the admin role on startup, other admins are demoted (app.conf)
+var AdminUsernames = map[string]bool{}
+
 // User represents user data
 // swagger:model User
 type User struct {
@@ -26,6 +39,90 @@ type User struct {
 
 	// swagger:ignore
 	HashedPassword []byte `json:"-"`
+
+	// auth tokens version, tokens with other versions are rejected
+	// swagger:ignore
+	TokenVersion int64 `json:"-"`
+
+	// swagger:ignore
+	Created int64 `json:"-"`
+
+	// swagger:ignore
+	Role string `json:"-"`
+
+	// disabled users can't login, their tokens and API keys are rejected
+	// swagger:ignore
+	Disabled bool `json:"-"`
+}
+
+// LoginAttempt represents failed login attempts of a username or client IP
+type LoginAttempt struct {
+	// Subject is the limiter key (user:<username> or ip:<address>)
+	Subject  string
+	Failures int
+	// Last failure time (unix milliseconds)
+	Last int64
+}
+
+// UserResponse represents a JSON response with the user profile
+// swagger:model UserResponse
+type UserResponse struct {
+	// Operation success flag
+	// required: true
+	// type: boolean
+	OK bool `json:"ok"`
+
+	// User ID
+	// required: true
+	// type: integer
+	ID int64 `json:"id"`
+
+	// Username
+	// required: true
+	// example: mkulish
+	Username string `json:"username"`
+
+	// Registration time (unix seconds)
+	// required: true
+	// type: integer
+	Created int64 `json:"created"`
+
+	// Number of user personal mazes
+	// required: true
+	// type: integer
+	Mazes int64 `json:"mazes"`
+
+	// Role: user or admin
+	// required: true
+	// type: string
+	// enum: user,admin
+	// example: user
+	Role string `json:"role"`
+}
+
+// PasswordChange represents password change data
+// swagger:model PasswordChange
+type PasswordChange struct {
+	// Current password
+	// required: true
+	// example: test123!
+	OldPassword string `json:"oldPassword"`
+
+	// New password
+	// required: true
+	// min length: 5
+	// max length: 15
+	// example: test456!
+	NewPassword string `json:"newPassword"`
+}
+
+// AccountDeletion represents account deletion confirmation
+// swagger:model AccountDeletion
+type AccountDeletion struct {
+	// Current password
+	// required: true
+	// example: test123!
+	Password string `json:"password"`
 }
 
 // LoginResponse represents login response JSON
@@ -36,10 +133,20 @@ type LoginResponse struct {
 	// type: boolean
 	OK bool `json:"ok"`
 
-	// Auth token
+	// Short-lived auth (access) token
 	// required: true
 	// type: string
 	Token string `json:"token"`
+
+	// Access token lifetime in seconds
+	// required: true
+	// type: integer
+	ExpiresIn int64 `json:"expiresIn"`
+
+	// Refresh token for POST /token/refresh, rotated on each refresh
+	// required: true
+	// type: string
+	RefreshToken string `json:"refreshToken"`
 }
 
 // Validate checks user data
@@ -56,3 +163,60 @@ func (u *User) Validate(v *revel.Validation) {
 		revel.MaxSize{Max: 15},
 	).Key("password")
 }
+
+// PreInsert hook is executed before inserting user into sqlite
+func (u *User) PreInsert(s gorp.SqlExecutor) error {
+	u.Created = time.Now().Unix()
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
+	return nil
+}
+
+// SyncAdminRoles grants the admin role to existing users with the usernames and demotes other admins,
+// returns the number of promoted and demoted users
+func SyncAdminRoles(s gorp.SqlExecutor, usernames map[string]bool) (int64, int64, error) {
+	names, args := []string{}, []interface{}{}
+	for username := range usernames {
+		names, args = append(names, "?"), append(args, username)
+	}
+	in := "(" + strings.Join(names, ", ") + ")"
+
+	var promoted int64
+	if len(names) > 0 {
+		res, err := s.Exec("UPDATE User SET Role = ? WHERE Role <> ? AND Username IN " + in,
+			append([]interface{}{RoleAdmin, RoleAdmin}, args...)...)
+		if err != nil {
+			return 0, 0, err
+		}
+		promoted, _ = res.RowsAffected()
+	}
+
+	res, err := s.Exec("UPDATE User SET Role = ? WHERE Role = ? AND Username NOT IN " + in,
+		append([]interface{}{RoleUser, RoleAdmin}, args...)...)
+	if err != nil {
+		return promoted, 0, err
+	}
+	demoted, _ := res.RowsAffected()
+	return promoted, demoted, nil
+}
+
+// Validate checks password change data
+func (p *PasswordChange) Validate(v *revel.Validation) {
+	v.Required(p.OldPassword).Key("oldPassword")
+
+	v.Check(p.NewPassword,
+		revel.Required{},
+		revel.MinSize{Min: 5},
+		revel.MaxSize{Max: 15},
+	).Key("newPassword")
+
+	if p.NewPassword != "" && p.NewPassword == p.OldPassword {
+		v.Error("Should differ from the current password").Key("newPassword")
+	}
+}
+
+// Validate checks account deletion confirmation
+func (d *AccountDeletion) Validate(v *revel.Validation) {
+	v.Required(d.Password).Key("password")
+}
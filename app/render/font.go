@@ -0,0 +1,53 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// labels bitmap font glyph size in font pixels
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+// glyphs are 3x5 bitmaps of digits and capital letters, rows from top to bottom
+var glyphs = map[rune]string{
+	'0': "111101101101111", '1': "010110010010111", '2': "111001111100111", '3': "111001111001111",
+	'4': "101101111001001", '5': "111100111001111", '6': "111100111101111", '7': "111001001001001",
+	'8': "111101111101111", '9': "111101111001111",
+	'A': "010101111101101", 'B': "110101110101110", 'C': "011100100100011", 'D': "110101101101110",
+	'E': "111100110100111", 'F': "111100110100100", 'G': "011100101101011", 'H': "101101111101101",
+	'I': "111010010010111", 'J': "001001001101010", 'K': "101101110101101", 'L': "100100100100111",
+	'M': "101111111101101", 'N': "110101101101101", 'O': "010101101101010", 'P': "110101110100100",
+	'Q': "010101101110011", 'R': "110101110101101", 'S': "011100010001110", 'T': "111010010010010",
+	'U': "101101101101111", 'V': "101101101101010", 'W': "101101111111101", 'X': "101101010101101",
+	'Y': "101101010010010", 'Z': "111001010100111",
+}
+
+// textWidth returns the text width in pixels, glyphs are separated by a font pixel
+func textWidth(text string, scale int) int {
+	if text == "" {
+		return 0
+	}
+	return (len(text) * (glyphWidth + 1) - 1) * scale
+}
+
+// drawText draws the text centered at the point
+func drawText(img draw.Image, text string, center image.Point, scale int, c color.Color) {
+	x := center.X - textWidth(text, scale) / 2
+	y := center.Y - glyphHeight * scale / 2
+	src := image.NewUniform(c)
+	for _, r := range text {
+		glyph := glyphs[r]
+		for i := 0; i < len(glyph); i++ {
+			if glyph[i] != '1' {
+				continue
+			}
+			px, py := x + i % glyphWidth * scale, y + i / glyphWidth * scale
+			draw.Draw(img, image.Rect(px, py, px + scale, py + scale), src, image.Point{}, draw.Src)
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
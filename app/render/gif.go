@@ -0,0 +1,95 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+
+	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/services"
+)
+
+// Solver trace animation frames limits
+const (
+	DefaultTraceFrames = 100
+	MaxTraceFrames     = 500
+)
+
+// frames delay in 1/100 of a second, the last frame with the solution path is shown longer
+const (
+	frameDelay     = 5
+	lastFrameDelay = 300
+)
+
+// TraceGIF animates the solver search events over the maze grid as GIF image.
+// Events are grouped into at most the given number of frames: pushed cells are drawn
+// as the frontier, popped cells as visited ones, the last frame shows the solution path.
+func TraceGIF(m *models.Maze, events []models.MazeTraceEvent, path []string, opts Options, frames int) ([]byte, error) {
+	s := newScene(m, path, opts)
+	theme := opts.Theme
+	palette := color.Palette{
+		theme.Background, theme.Grid, theme.Wall, theme.Entrance, theme.Exit,
+		theme.Path, theme.Label, theme.Frontier, theme.Visited,
+	}
+
+	canvas := image.NewPaletted(image.Rect(0, 0, s.width, s.height), palette)
+	s.drawGrid(canvas)
+	s.drawLabels(canvas)
+
+	// frames are drawn over the previous ones, only changed areas are encoded
+	anim := &gif.GIF{Config: image.Config{ColorModel: palette, Width: s.width, Height: s.height}}
+	add := func(r image.Rectangle, delay int) {
+		frame := image.NewPaletted(r, palette)
+		draw.Draw(frame, r, canvas, r.Min, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, delay)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	add(canvas.Bounds(), frameDelay)
+
+	// entrance and exits keep their colours
+	fixed := make([]bool, len(s.walls))
+	if s.entrance >= 0 {
+		fixed[s.entrance] = true
+	}
+	for _, idx := range s.exits {
+		fixed[idx] = true
+	}
+
+	perFrame := 1
+	if frames > 0 && len(events) > frames {
+		perFrame = (len(events) + frames - 1) / frames
+	}
+	for i := 0; i < len(events); i += perFrame {
+		var changed image.Rectangle
+		for j := i; j < i + perFrame && j < len(events); j++ {
+			idx := s.index(events[j].Cell)
+			if idx < 0 || fixed[idx] {
+				continue
+			}
+			colour := theme.Frontier
+			if events[j].Kind == services.TracePop {
+				colour = theme.Visited
+			}
+			// inside the grid lines
+			r := s.rect(idx)
+			r.Min = r.Min.Add(image.Pt(1, 1))
+			fill(canvas, r, colour)
+			changed = changed.Union(r)
+		}
+		if !changed.Empty() {
+			add(changed, frameDelay)
+		}
+	}
+
+	s.drawPath(canvas)
+	add(canvas.Bounds(), lastFrameDelay)
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Image draws the maze grid with the entrance, detected exits and the optional path as raster image
+func Image(m *models.Maze, path []string, opts Options) *image.RGBA {
+	s := newScene(m, path, opts)
+	img := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	s.drawGrid(img)
+	s.drawPath(img)
+	s.drawLabels(img)
+	return img
+}
+
+// PNG draws the maze as PNG image
+func PNG(m *models.Maze, path []string, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Image(m, path, opts)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fill(img draw.Image, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// drawGrid draws the background, grid lines, walls, entrance and exits
+func (s *scene) drawGrid(img draw.Image) {
+	theme, size := s.opts.Theme, s.opts.CellSize
+	fill(img, img.Bounds(), theme.Background)
+
+	for x := 0; x <= s.cols; x++ {
+		fill(img, image.Rect(s.left + x * size, s.top, s.left + x * size + 1, s.height), theme.Grid)
+	}
+	for y := 0; y <= s.rows; y++ {
+		fill(img, image.Rect(s.left, s.top + y * size, s.width, s.top + y * size + 1), theme.Grid)
+	}
+
+	for idx, wall := range s.walls {
+		if wall {
+			fill(img, s.rect(idx), theme.Wall)
+		}
+	}
+	if s.entrance >= 0 {
+		fill(img, s.rect(s.entrance), theme.Entrance)
+	}
+	for _, idx := range s.exits {
+		fill(img, s.rect(idx), theme.Exit)
+	}
+}
+
+// drawPath draws the path as thick segments between cell centers
+func (s *scene) drawPath(img draw.Image) {
+	w := s.pathWidth()
+	for i, idx := range s.path {
+		segment := s.rectAround(idx, w)
+		if i > 0 {
+			segment = segment.Union(s.rectAround(s.path[i - 1], w))
+		}
+		fill(img, segment, s.opts.Theme.Path)
+	}
+}
+
+// drawLabels draws column letters and row numbers (if enabled)
+func (s *scene) drawLabels(img draw.Image) {
+	if !s.opts.Labels {
+		return
+	}
+	size := s.opts.CellSize
+	for x := 0; x < s.cols; x++ {
+		drawText(img, columnLabel(x), image.Pt(s.left + x * size + size / 2, s.top / 2), s.scale, s.opts.Theme.Label)
+	}
+	for y := 0; y < s.rows; y++ {
+		drawText(img, strconv.Itoa(y + 1), image.Pt(s.left / 2, s.top + y * size + size / 2), s.scale, s.opts.Theme.Label)
+	}
+}
+
+// rectAround returns the square of the side w around the cell center
+func (s *scene) rectAround(idx, w int) image.Rectangle {
+	c := s.center(idx)
+	return image.Rect(c.X - w / 2, c.Y - w / 2, c.X - w / 2 + w, c.Y - w / 2 + w)
+}
@@ -0,0 +1,175 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/services"
+)
+
+// Theme represents maze rendering colours
+type Theme struct {
+	Background, Grid, Wall, Entrance, Exit, Path, Label color.RGBA
+	// solver trace cells
+	Frontier, Visited color.RGBA
+}
+
+// Themes are available rendering themes by name
+var Themes = map[string]Theme{
+	"light": {
+		Background: rgb(0xffffff),
+		Grid:       rgb(0xdddddd),
+		Wall:       rgb(0x333333),
+		Entrance:   rgb(0x4caf50),
+		Exit:       rgb(0xf44336),
+		Path:       rgb(0x2196f3),
+		Label:      rgb(0x555555),
+		Frontier:   rgb(0xfff59d),
+		Visited:    rgb(0xffcc80),
+	},
+	"dark": {
+		Background: rgb(0x1e1e1e),
+		Grid:       rgb(0x3a3a3a),
+		Wall:       rgb(0xd0d0d0),
+		Entrance:   rgb(0x66bb6a),
+		Exit:       rgb(0xef5350),
+		Path:       rgb(0x42a5f5),
+		Label:      rgb(0xaaaaaa),
+		Frontier:   rgb(0x827717),
+		Visited:    rgb(0x6d4c41),
+	},
+}
+
+// Rendering defaults and cell size limits (pixels)
+const (
+	DefaultTheme    = "light"
+	DefaultCellSize = 20
+	MinCellSize     = 4
+	MaxCellSize     = 64
+)
+
+// Options represents maze rendering options
+type Options struct {
+	// CellSize is the cell side in pixels
+	CellSize int
+	Theme    Theme
+	// Labels draws column letters and row numbers around the grid
+	Labels bool
+}
+
+// DefaultOptions returns options with the default cell size and theme
+func DefaultOptions() Options {
+	return Options{CellSize: DefaultCellSize, Theme: Themes[DefaultTheme]}
+}
+
+// ParseColour parses hex RGB colour (RRGGBB, # prefix is optional)
+func ParseColour(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	value, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("Incorrect colour: %s", s)
+	}
+	return rgb(uint32(value)), nil
+}
+
+func rgb(value uint32) color.RGBA {
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xff}
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// scene is the maze grid prepared for drawing
+type scene struct {
+	opts       Options
+	rows, cols int
+	walls      []bool
+	entrance   int
+	exits      []int
+	path       []int
+
+	// grid offset and image size in pixels
+	left, top     int
+	width, height int
+	// labels font scale
+	scale int
+}
+
+func newScene(m *models.Maze, path []string, opts Options) *scene {
+	s := &scene{opts: opts, entrance: -1}
+	s.rows, s.cols = models.ParseGridSize(m.GridSize)
+	s.walls = make([]bool, s.rows * s.cols)
+	for _, rawCell := range m.Walls {
+		if idx := s.index(rawCell); idx >= 0 {
+			s.walls[idx] = true
+		}
+	}
+	s.entrance = s.index(m.Entrance)
+	for _, rawCell := range services.AnalyzeReachability(m).Exits {
+		s.exits = append(s.exits, s.index(rawCell))
+	}
+	for _, rawCell := range path {
+		if idx := s.index(rawCell); idx >= 0 {
+			s.path = append(s.path, idx)
+		}
+	}
+
+	s.scale = opts.CellSize / 10
+	if s.scale < 1 {
+		s.scale = 1
+	}
+	if opts.Labels {
+		// widest row number and a padding
+		s.left = textWidth(strconv.Itoa(s.rows), s.scale) + 2 * s.scale
+		s.top = glyphHeight * s.scale + 2 * s.scale
+		if s.left < opts.CellSize {
+			s.left = opts.CellSize
+		}
+		if s.top < opts.CellSize {
+			s.top = opts.CellSize
+		}
+	}
+	s.width = s.left + s.cols * opts.CellSize
+	s.height = s.top + s.rows * opts.CellSize
+	return s
+}
+
+// index returns the cell index (-1 if the cell is out of the grid)
+func (s *scene) index(rawCell string) int {
+	x, y := services.CellCoords(rawCell)
+	if x < 0 || x >= s.cols || y < 0 || y >= s.rows {
+		return -1
+	}
+	return y * s.cols + x
+}
+
+// rect returns the cell rectangle in pixels
+func (s *scene) rect(idx int) image.Rectangle {
+	x, y := s.left + idx % s.cols * s.opts.CellSize, s.top + idx / s.cols * s.opts.CellSize
+	return image.Rect(x, y, x + s.opts.CellSize, y + s.opts.CellSize)
+}
+
+// center returns the cell center in pixels
+func (s *scene) center(idx int) image.Point {
+	r := s.rect(idx)
+	return image.Pt((r.Min.X + r.Max.X) / 2, (r.Min.Y + r.Max.Y) / 2)
+}
+
+// pathWidth returns the path line width in pixels
+func (s *scene) pathWidth() int {
+	if w := s.opts.CellSize / 4; w > 1 {
+		return w
+	}
+	return 1
+}
+
+// columnLabel returns spreadsheet-style column letters
+func columnLabel(x int) string {
+	name := services.CellName(x, 0)
+	return name[:len(name) - 1]
+}
@@ -0,0 +1,77 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// SVG draws the maze grid with the entrance, detected exits and the optional path as SVG image
+func SVG(m *models.Maze, path []string, opts Options) []byte {
+	s := newScene(m, path, opts)
+	theme, size := opts.Theme, opts.CellSize
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		s.width, s.height, s.width, s.height)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`+"\n", s.width, s.height, hex(theme.Background))
+
+	// grid lines
+	fmt.Fprintf(&buf, `<g stroke="%s" stroke-width="1">`+"\n", hex(theme.Grid))
+	for x := 0; x <= s.cols; x++ {
+		fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n",
+			s.left + x * size, s.top, s.left + x * size, s.height)
+	}
+	for y := 0; y <= s.rows; y++ {
+		fmt.Fprintf(&buf, `<line x1="%d" y1="%d" x2="%d" y2="%d"/>`+"\n",
+			s.left, s.top + y * size, s.width, s.top + y * size)
+	}
+	buf.WriteString("</g>\n")
+
+	cell := func(idx int, fill string) {
+		r := s.rect(idx)
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", r.Min.X, r.Min.Y, size, size, fill)
+	}
+	for idx, wall := range s.walls {
+		if wall {
+			cell(idx, hex(theme.Wall))
+		}
+	}
+	if s.entrance >= 0 {
+		cell(s.entrance, hex(theme.Entrance))
+	}
+	for _, idx := range s.exits {
+		cell(idx, hex(theme.Exit))
+	}
+
+	if len(s.path) > 0 {
+		var points bytes.Buffer
+		for i, idx := range s.path {
+			if i > 0 {
+				points.WriteByte(' ')
+			}
+			c := s.center(idx)
+			fmt.Fprintf(&points, "%d,%d", c.X, c.Y)
+		}
+		fmt.Fprintf(&buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%d" stroke-linecap="round" stroke-linejoin="round"/>`+"\n",
+			points.String(), hex(theme.Path), s.pathWidth())
+	}
+
+	if opts.Labels {
+		fontSize := glyphHeight * s.scale * 3 / 2
+		fmt.Fprintf(&buf, `<g fill="%s" font-family="monospace" font-size="%d" text-anchor="middle" dominant-baseline="central">`+"\n",
+			hex(theme.Label), fontSize)
+		for x := 0; x < s.cols; x++ {
+			fmt.Fprintf(&buf, `<text x="%d" y="%d">%s</text>`+"\n", s.left + x * size + size / 2, s.top / 2, columnLabel(x))
+		}
+		for y := 0; y < s.rows; y++ {
+			fmt.Fprintf(&buf, `<text x="%d" y="%d">%s</text>`+"\n", s.left / 2, s.top + y * size + size / 2, strconv.Itoa(y + 1))
+		}
+		buf.WriteString("</g>\n")
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// ASCII maze symbols
+const (
+	ASCIIWall     = '#'
+	ASCIIOpen     = '.'
+	ASCIIEntrance = 'S'
+	// explicit exit cell, bottom-edge exit policy is used without it
+	ASCIIExit     = 'E'
+	// solution path overlay, parsed as an open cell
+	ASCIIPath     = '*'
+)
+
+// ParseASCII parses the maze grid drawn with ASCII symbols, one line per row.
+// Blank lines and trailing spaces are ignored, all rows should have the same length.
+func ParseASCII(text string) (*models.Maze, error) {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("Empty grid")
+	}
+
+	m := &models.Maze{
+		GridSize: fmt.Sprintf("%dx%d", len(lines), len(lines[0])),
+		Walls: []string{},
+	}
+	for y, line := range lines {
+		if len(line) != len(lines[0]) {
+			return nil, fmt.Errorf("Row %d has %d cells, expected %d", y + 1, len(line), len(lines[0]))
+		}
+
+		for x := 0; x < len(line); x++ {
+			rawCell := encodeCell(cell{x: x, y: y})
+			switch line[x] {
+			case ASCIIWall:
+				m.Walls = append(m.Walls, rawCell)
+			case ASCIIOpen, ASCIIPath:
+			case ASCIIEntrance:
+				if m.Entrance != "" {
+					return nil, fmt.Errorf("Multiple entrances: %s, %s", m.Entrance, rawCell)
+				}
+				m.Entrance = rawCell
+			case ASCIIExit:
+				if m.Exit != "" {
+					return nil, fmt.Errorf("Multiple exits: %s, %s", m.Exit, rawCell)
+				}
+				m.Exit, m.ExitPolicy = rawCell, models.ExitCell
+			default:
+				return nil, fmt.Errorf("Unknown symbol '%c' at %s", line[x], rawCell)
+			}
+		}
+	}
+
+	if m.Entrance == "" {
+		return nil, fmt.Errorf("Missing entrance (%c)", ASCIIEntrance)
+	}
+	return m, nil
+}
+
+// RenderASCII draws the maze grid with ASCII symbols, path cells are marked with the overlay symbol
+func RenderASCII(m *models.Maze, path []string) string {
+	g := newGrid(m)
+	cells := make([]byte, len(g.walls))
+	for idx, wall := range g.walls {
+		cells[idx] = ASCIIOpen
+		if wall {
+			cells[idx] = ASCIIWall
+		}
+	}
+
+	mark := func(rawCell string, symbol byte) {
+		c := parseCell(rawCell)
+		if c.x >= 0 && c.x < g.width && c.y >= 0 && c.y < g.height {
+			cells[g.index(c.x, c.y)] = symbol
+		}
+	}
+	for _, rawCell := range path {
+		mark(rawCell, ASCIIPath)
+	}
+	if m.ExitPolicy == models.ExitCell {
+		mark(m.Exit, ASCIIExit)
+	}
+	mark(m.Entrance, ASCIIEntrance)
+
+	var res strings.Builder
+	for y := 0; y < g.height; y++ {
+		res.Write(cells[y * g.width:(y + 1) * g.width])
+		res.WriteByte('\n')
+	}
+	return res.String()
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Budget limits exhaustive path searches
+type Budget struct {
+	// Nodes is the max number of expanded search nodes (0 - unlimited)
+	Nodes int
+	// Timeout is the max search duration (0 - unlimited)
+	Timeout time.Duration
+}
+
+// DefaultBudget is used for the longest path search, overridden from app.conf on start
+var DefaultBudget = Budget{Nodes: 2000000, Timeout: 5 * time.Second}
+
+// grids with up to memoCells open cells use memoization of exact subresults
+const memoCells = 64
+
+// memoization is limited to memoLimit entries to bound memory usage
+const memoLimit = 1 << 20
+
+// LongestPath returns the longest simple path from the entrance to the exit.
+// Search is exact (DFS with branch and bound pruning), the optimal flag is false
+// when the budget was exhausted and the result is the best path found so far.
+func LongestPath(m *models.Maze, budget Budget) ([]string, bool, error) {
+	return longestPath(m, budget, nil)
+}
+
+// longestPath searches for the longest path reporting depth-first steps to the tracer (optional)
+func longestPath(m *models.Maze, budget Budget, tracer Tracer) ([]string, bool, error) {
+	g := newGrid(m)
+	start := parseCell(m.Entrance)
+	from := g.index(start.x, start.y)
+	if g.exits[from] {
+		// entrance is the exit
+		return []string{m.Entrance}, true, nil
+	}
+
+	if err := CheckExits(m); err != nil {
+		return nil, false, err
+	}
+	_, exits := g.reach(from)
+
+	s := newLongestSearch(g, budget)
+	s.tracer = tracer
+	s.visited[from] = true
+	s.exit = -1
+	if len(exits) == 1 {
+		s.exit = exits[0]
+	}
+
+	// shortest path is a lower bound and a fallback result
+	seed, err := AStarPath(m)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, rawCell := range seed {
+		c := parseCell(rawCell)
+		s.best = append(s.best, g.index(c.x, c.y))
+	}
+
+	s.path = append(s.path, from)
+	s.search(from)
+
+	res := make([]string, len(s.best))
+	for i, idx := range s.best {
+		res[i] = encodeCell(g.cell(idx))
+	}
+	return res, !s.exhausted, nil
+}
+
+type memoKey struct {
+	idx  int
+	comp uint64
+}
+
+type memoEntry struct {
+	steps int
+	next  int
+}
+
+type longestSearch struct {
+	g        *grid
+	budget   Budget
+	deadline time.Time
+	nodes    int
+	// search was interrupted by the budget
+	exhausted bool
+
+	// single reachable exit (-1 if many)
+	exit    int
+	visited []bool
+	path    []int
+	best    []int
+
+	// memo bit index of each passable cell (small grids only)
+	bits []int
+	memo map[memoKey]memoEntry
+
+	// flood fill buffers
+	mark  []int
+	epoch int
+	queue []int
+
+	tracer Tracer
+}
+
+func newLongestSearch(g *grid, budget Budget) *longestSearch {
+	s := &longestSearch{
+		g:       g,
+		budget:  budget,
+		visited: make([]bool, g.width * g.height),
+		mark:    make([]int, g.width * g.height),
+	}
+	if budget.Timeout > 0 {
+		s.deadline = time.Now().Add(budget.Timeout)
+	}
+
+	open := 0
+	for idx := range s.visited {
+		if g.passable(idx) {
+			open++
+		}
+	}
+	if open <= memoCells {
+		s.memo = make(map[memoKey]memoEntry)
+		s.bits = make([]int, len(s.visited))
+		bit := 0
+		for idx := range s.bits {
+			s.bits[idx] = -1
+			if g.passable(idx) {
+				s.bits[idx] = bit
+				bit++
+			}
+		}
+	}
+	return s
+}
+
+// search returns max steps from the path end to the exit (-1 if unreachable)
+// and whether the result is exact (not affected by pruning or budget)
+func (s *longestSearch) search(from int) (int, bool) {
+	if s.spent() {
+		return -1, false
+	}
+	if s.tracer != nil {
+		c := s.g.cell(from)
+		c.steps = len(s.path) - 1
+		trace(s.tracer, TracePush, c)
+		defer trace(s.tracer, TracePop, c)
+	}
+
+	comp, exits := s.component(from)
+	bound := s.bound(from, comp)
+	if len(exits) == 0 || bound < 0 {
+		return -1, true
+	}
+	depth := len(s.path) - 1
+	if depth + bound <= len(s.best) - 1 {
+		// can't improve the best path found so far
+		return -1, false
+	}
+
+	var key memoKey
+	if s.memo != nil {
+		key = memoKey{from, s.mask(comp)}
+		if entry, found := s.memo[key]; found {
+			if depth + entry.steps > len(s.best) - 1 {
+				s.replay(from, entry)
+			}
+			return entry.steps, true
+		}
+	}
+
+	steps, next, exact := -1, -1, true
+	for _, n := range s.g.neighbours(from) {
+		if s.g.exits[n] {
+			steps, next = 1, n
+			if depth + 1 > len(s.best) - 1 {
+				s.best = append(append(s.best[:0], s.path...), n)
+			}
+			break
+		}
+	}
+	for _, n := range s.g.neighbours(from) {
+		if s.visited[n] || !s.g.passable(n) {
+			continue
+		}
+
+		s.visited[n] = true
+		s.path = append(s.path, n)
+		res, ok := s.search(n)
+		s.path = s.path[:len(s.path) - 1]
+		s.visited[n] = false
+
+		exact = exact && ok
+		if res >= 0 && res + 1 > steps {
+			steps, next = res + 1, n
+		}
+		if s.exhausted {
+			return steps, false
+		}
+	}
+
+	if exact && s.memo != nil && len(s.memo) < memoLimit {
+		if _, found := s.memo[s.childKey(next)]; found || (next >= 0 && s.g.exits[next]) {
+			s.memo[key] = memoEntry{steps, next}
+		}
+	}
+	return steps, exact
+}
+
+// spent counts expanded node and checks the search budget
+func (s *longestSearch) spent() bool {
+	s.nodes++
+	if s.budget.Nodes > 0 && s.nodes > s.budget.Nodes {
+		s.exhausted = true
+	}
+	if !s.deadline.IsZero() && s.nodes % 1024 == 0 && time.Now().After(s.deadline) {
+		s.exhausted = true
+	}
+	return s.exhausted
+}
+
+// component flood fills unvisited open cells reachable from the cell,
+// returns the component cells and reachable exit cells
+func (s *longestSearch) component(from int) ([]int, []int) {
+	s.epoch++
+	s.mark[from] = s.epoch
+	s.queue = append(s.queue[:0], from)
+
+	var exits []int
+	for i := 0; i < len(s.queue); i++ {
+		for _, n := range s.g.neighbours(s.queue[i]) {
+			if s.mark[n] == s.epoch || s.visited[n] || s.g.walls[n] {
+				continue
+			}
+			s.mark[n] = s.epoch
+			if s.g.exits[n] {
+				exits = append(exits, n)
+			} else {
+				s.queue = append(s.queue, n)
+			}
+		}
+	}
+	return s.queue[1:], exits
+}
+
+// bound returns the upper bound of steps from the cell to the exit through the component.
+// Path cells alternate in checkerboard colours, which also fixes the parity of the path length.
+func (s *longestSearch) bound(from int, comp []int) int {
+	colour := s.g.colour(from)
+	same, other := 0, 0
+	for _, idx := range comp {
+		if s.g.colour(idx) == colour {
+			same++
+		} else {
+			other++
+		}
+	}
+
+	// k intermediate cells start with the other colour
+	k := 2 * other
+	if 2 * same + 1 < k {
+		k = 2 * same + 1
+	}
+	// the cell before the exit has the colour opposite to the exit one
+	if s.exit >= 0 && (k % 2 == 1) != (s.g.colour(s.exit) == colour) {
+		k--
+	}
+	if k < 0 {
+		return -1
+	}
+	return k + 1
+}
+
+// mask encodes the component cells as a bitset
+func (s *longestSearch) mask(comp []int) uint64 {
+	var res uint64
+	for _, idx := range comp {
+		res |= 1 << uint(s.bits[idx])
+	}
+	return res
+}
+
+// childKey returns the memo key of the cell as the next step from the path end
+func (s *longestSearch) childKey(n int) memoKey {
+	if n < 0 || s.g.exits[n] {
+		return memoKey{-1, 0}
+	}
+	s.visited[n] = true
+	comp, _ := s.component(n)
+	key := memoKey{n, s.mask(comp)}
+	s.visited[n] = false
+	return key
+}
+
+// replay stores the best path following the memoized steps from the path end
+func (s *longestSearch) replay(from int, entry memoEntry) {
+	s.best = append(s.best[:0], s.path...)
+	var steps []int
+	for !s.g.exits[entry.next] {
+		from = entry.next
+		s.visited[from] = true
+		steps = append(steps, from)
+		s.best = append(s.best, from)
+
+		comp, _ := s.component(from)
+		entry = s.memo[memoKey{from, s.mask(comp)}]
+	}
+	s.best = append(s.best, entry.next)
+
+	for _, idx := range steps {
+		s.visited[idx] = false
+	}
+}
@@ -1,8 +1,6 @@
 package services
 
 import (
-	"container/heap"
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -22,7 +20,7 @@ func ValidateMazeGrid(m *models.Maze, v *revel.Validation) {
 			v.Error("Incorrect entrance: %s", m.Entrance).Key("entrance")
 		}
 
-		walls := make(map[string]struct{}, len(m.Walls))
+		walls := make(map[cell]struct{}, len(m.Walls))
 		for _, rawCell := range m.Walls {
 			// validity and bounds check
 			cell := parseCell(rawCell)
@@ -36,85 +34,42 @@ func ValidateMazeGrid(m *models.Maze, v *revel.Validation) {
 			}
 
 			// duplicates check
-			if _, found := walls[rawCell]; found {
+			if _, found := walls[cell]; found {
 				v.Error("Duplicate wall cell: %s", rawCell).Key("walls")
 			}
-			walls[rawCell] = struct{}{}
+			walls[cell] = struct{}{}
 		}
-	}
-}
-
-// SolveMaze returns maze solution path (if any)
-// complexity: x * y * log(x * y)
-func SolveMaze(m *models.Maze, min bool) ([]string, error) {
-	width, heigth := size(m)
-
-	// init walls and explored cells matrices
-	walls, explored := make([][]bool, width), make([][]bool, width)
-	for i := 0; i < width; i++ {
-		walls[i], explored[i] = make([]bool, heigth), make([]bool, heigth)
-	}
-	for _, rawCell := range m.Walls {
-		cell := parseCell(rawCell)
-		walls[cell.x][cell.y] = true
-	}
-
-	// potential exit coordinate
-	// max steps path lookup will find different solution before finish to detect multiple exits 
-	exitX := -1
-	var solution *cell
 
-	start := parseCell(m.Entrance)
-	h := cellHeap{[]*cell{&start}, min}
-	heap.Init(&h)
-
-	for h.Len() > 0 {
-		// pop closest/farest cell from priority queue for shortest/longest path search
-		next := heap.Pop(&h).(*cell)
-		explored[next.x][next.y] = true
-		if next.y == heigth - 1 {
-			solution = next
-			break
-		}
-
-		// add all adjacent cells to the priority queue
-		if next.x > 0 && ! walls[next.x - 1][next.y] && ! explored[next.x - 1][next.y] {
-			heap.Push(&h, &cell{x: next.x - 1, y: next.y, parent: next, steps: next.steps + 1})
-		}
-		if next.y > 0 && ! walls[next.x][next.y - 1] && ! explored[next.x][next.y - 1] {
-			heap.Push(&h, &cell{x: next.x, y: next.y - 1, parent: next, steps: next.steps + 1})
-		}
-		if next.x < width - 1 && ! walls[next.x + 1][next.y] && ! explored[next.x + 1][next.y] {
-			heap.Push(&h, &cell{x: next.x + 1, y: next.y, parent: next, steps: next.steps + 1})
-		}
-		if next.y < heigth - 1 && ! walls[next.x][next.y + 1] && ! explored[next.x][next.y + 1] {
-			heap.Push(&h, &cell{x: next.x, y: next.y + 1, parent: next, steps: next.steps + 1})
-
-			if next.y == heigth - 2 {
-				// approaching exit row
-				if exitX >= 0 && exitX != next.x {
-					// another potential exit path was already found in max steps lookup
-					return nil, fmt.Errorf("Maze has multiple exits")
-				}
-				exitX = next.x
+		if m.ExitPolicy == models.ExitCell {
+			exit := parseCell(m.Exit)
+			if exit.x < 0 || exit.x >= width || exit.y < 0 || exit.y >= height {
+				v.Error("Incorrect exit: %s", m.Exit).Key("exit")
+			} else if exit.x == start.x && exit.y == start.y {
+				v.Error("Exit is the entrance cell: %s", m.Exit).Key("exit")
+			} else if _, found := walls[exit]; found {
+				v.Error("Exit is a wall cell: %s", m.Exit).Key("exit")
 			}
 		}
 	}
 
-	if solution == nil {
-		return nil, fmt.Errorf("Maze doesn't have a solution")
+	if ! v.HasErrors() {
+		// exits check independent of the solver search order
+		if err := CheckExits(m); err != nil {
+			v.Error(err.Error()).Key("walls")
+		}
 	}
+}
 
-	// trace back and reverse result path
-	var res []string
-	for solution != nil {
-		res = append(res, encodeCell(*solution))
-		solution = solution.parent
+// SolveMaze returns maze solution path (if any) and whether it is proven optimal
+// using the default min/max steps algorithm
+func SolveMaze(m *models.Maze, min bool) ([]string, bool, error) {
+	if err := CheckExits(m); err != nil {
+		return nil, false, err
 	}
-	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
-		res[i], res[j] = res[j], res[i]
+	if min {
+		return FindSolver(MinSteps, DefaultMinAlgorithm).Solve(m)
 	}
-	return res, nil
+	return FindSolver(MaxSteps, DefaultMaxAlgorithm).Solve(m)
 }
 
 type cell struct {
@@ -122,56 +77,153 @@ type cell struct {
 	steps	int
 	parent	*cell
 }
+// parseCell parses spreadsheet-style cell notation: A2 -> x:0, y:1, AB10 -> x:27, y:9
+// returns negative coordinates for incorrect cells
 func parseCell(rawCell string) cell {
-	x := int(rawCell[0] - 'A')
-	y,_ := strconv.Atoi(rawCell[1:])
-	// 0-based: A2 -> x:0, y:1
-	return cell{x: x, y: y-1}
+	x, y := models.CellCoords(rawCell)
+	return cell{x: x, y: y}
 }
 func encodeCell(cell cell) string {
-	return fmt.Sprintf("%c%d", rune('A' + cell.x), cell.y+1)
+	return models.CellName(cell.x, cell.y)
 }
 
-type cellHeap struct {
-	items []*cell
-	min bool
+// CellCoords returns 0-based coordinates of the cell, negative if the cell is incorrect
+func CellCoords(rawCell string) (int, int) {
+	return models.CellCoords(rawCell)
+}
+
+// CellName returns spreadsheet-style name of the cell by 0-based coordinates
+func CellName(x, y int) string {
+	return models.CellName(x, y)
+}
+
+func size(m *models.Maze) (int, int) {
+	size := strings.Split(m.GridSize, "x")
+	width, _ := strconv.Atoi(size[1])
+	height, _ := strconv.Atoi(size[0])
+	return width, height
 }
-func (h *cellHeap) Len() int {
-	return len(h.items)
+
+// grid is a flat representation of the maze grid
+type grid struct {
+	width, height int
+	walls         []bool
+	// exits marks open cells ending the path according to the maze exit policy
+	exits         []bool
 }
-func (h *cellHeap) Less(i, j int) bool {
-	var res bool
-	if h.items[i].y == h.items[j].y {
-		// cells with the same distance to exit are sorted by their path steps count
-		res = h.items[i].steps > h.items[j].steps
-	} else {
-		// otherwise - by distance to exit
-		res = h.items[i].y < h.items[j].y
+
+func newGrid(m *models.Maze) *grid {
+	width, height := size(m)
+	g := &grid{
+		width: width,
+		height: height,
+		walls: make([]bool, width * height),
+		exits: make([]bool, width * height),
+	}
+	for _, rawCell := range m.Walls {
+		c := parseCell(rawCell)
+		g.walls[g.index(c.x, c.y)] = true
 	}
 
-	if h.min {
-		// reverse order to choose between shortest/longest path
-		return ! res
+	start := parseCell(m.Entrance)
+	switch m.ExitPolicy {
+	case models.ExitAnyEdge, models.ExitNearest:
+		for idx := range g.exits {
+			c := g.cell(idx)
+			edge := c.x == 0 || c.y == 0 || c.x == width - 1 || c.y == height - 1
+			g.exits[idx] = edge && (c.x != start.x || c.y != start.y)
+		}
+	case models.ExitCell:
+		c := parseCell(m.Exit)
+		g.exits[g.index(c.x, c.y)] = true
+	default:
+		for x := 0; x < width; x++ {
+			g.exits[g.index(x, height - 1)] = true
+		}
 	}
-	return res
+	for idx, wall := range g.walls {
+		g.exits[idx] = g.exits[idx] && !wall
+	}
+	return g
 }
-func (h cellHeap) Swap(i, j int){
-	h.items[i], h.items[j] = h.items[j], h.items[i]
+
+func (g *grid) index(x, y int) int {
+	return y * g.width + x
 }
-func (h *cellHeap) Push(x any) {
-	h.items = append(h.items, x.(*cell))
+
+func (g *grid) cell(idx int) cell {
+	return cell{x: idx % g.width, y: idx / g.width}
 }
-func (h *cellHeap) Pop() any {
-	n := len(h.items)
-	x := h.items[n - 1]
-	h.items[n - 1] = nil
-	h.items = h.items[: n - 1]
-	return x
+
+// passable checks if the path can go through the cell
+func (g *grid) passable(idx int) bool {
+	return !g.walls[idx] && !g.exits[idx]
 }
 
-func size(m *models.Maze) (int, int) {
-	size := strings.Split(m.GridSize, "x")
-	width, _ := strconv.Atoi(size[1])
-	height, _ := strconv.Atoi(size[0])
-	return width, height
+// exitDistances returns the distance from each cell to the nearest exit ignoring walls,
+// used as an admissible heuristic
+func (g *grid) exitDistances() []int {
+	dist := make([]int, len(g.walls))
+	var queue []int
+	for idx := range dist {
+		dist[idx] = -1
+		if g.exits[idx] {
+			dist[idx] = 0
+			queue = append(queue, idx)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		for _, n := range g.neighbours(queue[i]) {
+			if dist[n] < 0 {
+				dist[n] = dist[queue[i]] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+	for idx := range dist {
+		if dist[idx] < 0 {
+			// no exits at all
+			dist[idx] = 0
+		}
+	}
+	return dist
+}
+
+func (g *grid) colour(idx int) int {
+	return (idx % g.width + idx / g.width) % 2
+}
+
+// neighbours returns horizontally and vertically adjacent cells
+func (g *grid) neighbours(idx int) []int {
+	res := make([]int, 0, 4)
+	x, y := idx % g.width, idx / g.width
+	if x > 0 {
+		res = append(res, idx - 1)
+	}
+	if y > 0 {
+		res = append(res, idx - g.width)
+	}
+	if x < g.width - 1 {
+		res = append(res, idx + 1)
+	}
+	if y < g.height - 1 {
+		res = append(res, idx + g.width)
+	}
+	return res
+}
+
+// trace returns the path to the cell following parent cells
+func (g *grid) trace(parents []int, idx int) []string {
+	var res []string
+	for {
+		res = append(res, encodeCell(g.cell(idx)))
+		if parents[idx] == idx {
+			break
+		}
+		idx = parents[idx]
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
 }
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// PatchMaze applies JSON Merge Patch (RFC 7386) to the maze data,
+// addWalls and removeWalls patch members add or remove separate wall cells
+func PatchMaze(m *models.Maze, patch []byte) error {
+	var members map[string]any
+	if err := json.Unmarshal(patch, &members); err != nil {
+		return err
+	}
+	if members == nil {
+		return fmt.Errorf("patch should be an object")
+	}
+
+	var walls struct {
+		Add    []string `json:"addWalls"`
+		Remove []string `json:"removeWalls"`
+	}
+	if err := json.Unmarshal(patch, &walls); err != nil {
+		return err
+	}
+	delete(members, "addWalls")
+	delete(members, "removeWalls")
+	// maze id and creation time can't be changed
+	delete(members, "id")
+	delete(members, "created")
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var doc any
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(MergePatch(doc, members)); err != nil {
+		return err
+	}
+
+	patched := models.Maze{ID: m.ID, OwnerID: m.OwnerID}
+	if err = json.Unmarshal(data, &patched); err != nil {
+		return err
+	}
+
+	removed := make(map[string]struct{}, len(walls.Remove))
+	for _, rawCell := range walls.Remove {
+		removed[rawCell] = struct{}{}
+	}
+	cells := []string{}
+	for _, rawCell := range patched.Walls {
+		if _, found := removed[rawCell]; !found {
+			cells = append(cells, rawCell)
+		}
+	}
+	patched.Walls = append(cells, walls.Add...)
+
+	*m = patched
+	return nil
+}
+
+// MergePatch returns the target document with the patch applied according to RFC 7386
+func MergePatch(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	doc, ok := target.(map[string]any)
+	if !ok {
+		doc = map[string]any{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(doc, name)
+		} else {
+			doc[name] = MergePatch(doc[name], value)
+		}
+	}
+	return doc
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Reachability represents cells reachable from the maze entrance
+type Reachability struct {
+	// Number of reachable cells the path can go through (exits excluded)
+	Cells int
+	// Reachable exit cells in grid order (rows first)
+	Exits []string
+}
+
+// AnalyzeReachability flood fills the grid from the entrance, exit cells end the path and are not passed through
+// complexity: x * y
+func AnalyzeReachability(m *models.Maze) Reachability {
+	g := newGrid(m)
+	start := parseCell(m.Entrance)
+
+	cells, exits := g.reach(g.index(start.x, start.y))
+	res := Reachability{Cells: len(cells), Exits: make([]string, len(exits))}
+	for i, idx := range exits {
+		res.Exits[i] = encodeCell(g.cell(idx))
+	}
+	return res
+}
+
+// CheckExits returns an error if the maze has no reachable exit
+// or multiple reachable exits not allowed by the exit policy
+func CheckExits(m *models.Maze) error {
+	reach := AnalyzeReachability(m)
+	if len(reach.Exits) == 0 {
+		return fmt.Errorf("Maze doesn't have a solution")
+	}
+	if len(reach.Exits) > 1 && m.ExitPolicy != models.ExitNearest {
+		return fmt.Errorf("Maze has multiple exits: %s", strings.Join(reach.Exits, ", "))
+	}
+	return nil
+}
+
+// reach returns passable cells and sorted exit cells reachable from the cell
+func (g *grid) reach(from int) ([]int, []int) {
+	if g.exits[from] {
+		// entrance is the exit
+		return nil, []int{from}
+	}
+
+	marked := make([]bool, len(g.walls))
+	marked[from] = true
+	queue := []int{from}
+	for i := 0; i < len(queue); i++ {
+		for _, n := range g.neighbours(queue[i]) {
+			if marked[n] || g.walls[n] {
+				continue
+			}
+			marked[n] = true
+			if !g.exits[n] {
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	var exits []int
+	for idx, exit := range g.exits {
+		if exit && marked[idx] {
+			exits = append(exits, idx)
+		}
+	}
+	return queue, exits
+}
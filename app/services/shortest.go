@@ -0,0 +1,347 @@
+package services
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// BFSPath returns the shortest path from the entrance to the exit using breadth-first search
+// complexity: x * y
+func BFSPath(m *models.Maze) ([]string, error) {
+	g := newGrid(m)
+	start := parseCell(m.Entrance)
+	from := g.index(start.x, start.y)
+
+	parents := make([]int, len(g.walls))
+	for i := range parents {
+		parents[i] = -1
+	}
+	parents[from] = from
+
+	queue := []int{from}
+	for i := 0; i < len(queue); i++ {
+		next := queue[i]
+		if g.exits[next] {
+			return g.trace(parents, next), nil
+		}
+
+		for _, n := range g.neighbours(next) {
+			if g.walls[n] || parents[n] >= 0 {
+				continue
+			}
+			parents[n] = next
+			queue = append(queue, n)
+		}
+	}
+	return nil, fmt.Errorf("Maze doesn't have a solution")
+}
+
+// AStarPath returns the shortest path from the entrance to the exit using A* search,
+// heuristic is the distance to the nearest exit ignoring walls (admissible and consistent)
+// complexity: x * y * log(x * y)
+func AStarPath(m *models.Maze) ([]string, error) {
+	return bestFirstPath(m, true, nil)
+}
+
+// DijkstraPath returns the shortest path from the entrance to the exit using
+// uniform cost search (Dijkstra algorithm with unit step costs)
+// complexity: x * y * log(x * y)
+func DijkstraPath(m *models.Maze) ([]string, error) {
+	return bestFirstPath(m, false, nil)
+}
+
+// bestFirstPath expands cells in the order of path steps and (if informed) distance to the exit,
+// heap pushes and pops are reported to the tracer (optional)
+func bestFirstPath(m *models.Maze, informed bool, tracer Tracer) ([]string, error) {
+	g := newGrid(m)
+	start := parseCell(m.Entrance)
+	explored := make([]bool, len(g.walls))
+
+	estimate := func(c *cell) int {
+		return c.steps
+	}
+	if informed {
+		dist := g.exitDistances()
+		estimate = func(c *cell) int {
+			return c.steps + dist[g.index(c.x, c.y)]
+		}
+	}
+
+	h := cellHeap{estimate: estimate, tracer: tracer}
+	heap.Push(&h, &start)
+
+	for h.Len() > 0 {
+		// pop the cell with the lowest estimated path length
+		next := heap.Pop(&h).(*cell)
+		idx := g.index(next.x, next.y)
+		if explored[idx] {
+			continue
+		}
+		explored[idx] = true
+		if g.exits[idx] {
+			return traceCell(next), nil
+		}
+
+		// add all adjacent cells to the priority queue
+		for _, n := range g.neighbours(idx) {
+			if g.walls[n] || explored[n] {
+				continue
+			}
+			c := g.cell(n)
+			heap.Push(&h, &cell{x: c.x, y: c.y, parent: next, steps: next.steps + 1})
+		}
+	}
+	return nil, fmt.Errorf("Maze doesn't have a solution")
+}
+
+// BidirectionalPath returns the shortest path from the entrance to the exit using
+// breadth-first search from both the entrance and all exit row cells
+// complexity: x * y
+func BidirectionalPath(m *models.Maze) ([]string, error) {
+	g := newGrid(m)
+	start := parseCell(m.Entrance)
+	from := g.index(start.x, start.y)
+	if g.exits[from] {
+		return []string{m.Entrance}, nil
+	}
+
+	fwd, bwd := newFrontier(g, true), newFrontier(g, false)
+	fwd.add(from, from)
+	for idx, exit := range g.exits {
+		if exit {
+			bwd.add(idx, idx)
+		}
+	}
+
+	for len(fwd.layer) > 0 && len(bwd.layer) > 0 {
+		// expand the smaller frontier layer
+		a, b := fwd, bwd
+		if len(bwd.layer) < len(fwd.layer) {
+			a, b = bwd, fwd
+		}
+		if meetA, meetB := a.expand(b); meetA >= 0 {
+			if !a.forward {
+				meetA, meetB = meetB, meetA
+			}
+			path := g.trace(fwd.parents, meetA)
+			for idx := meetB; ; idx = bwd.parents[idx] {
+				path = append(path, encodeCell(g.cell(idx)))
+				if bwd.parents[idx] == idx {
+					break
+				}
+			}
+			return path, nil
+		}
+	}
+	return nil, fmt.Errorf("Maze doesn't have a solution")
+}
+
+// frontier is a single direction state of the bidirectional search
+type frontier struct {
+	g       *grid
+	forward bool
+	parents []int
+	dist    []int
+	layer   []int
+}
+
+func newFrontier(g *grid, forward bool) *frontier {
+	f := &frontier{g: g, forward: forward, parents: make([]int, len(g.walls)), dist: make([]int, len(g.walls))}
+	for i := range f.parents {
+		f.parents[i] = -1
+	}
+	return f
+}
+
+func (f *frontier) add(idx, parent int) {
+	f.parents[idx] = parent
+	f.dist[idx] = f.dist[parent] + 1
+	if idx == parent {
+		f.dist[idx] = 0
+	}
+	f.layer = append(f.layer, idx)
+}
+
+// expand expands the whole current layer and returns the shortest edge
+// connecting it with the other direction (-1 if not met yet)
+func (f *frontier) expand(other *frontier) (int, int) {
+	layer := f.layer
+	f.layer = nil
+
+	meetA, meetB, best := -1, -1, 0
+	for _, idx := range layer {
+		for _, n := range f.g.neighbours(idx) {
+			// exit cells can only be the path end
+			if f.g.walls[n] || (!f.forward && f.g.exits[n]) {
+				continue
+			}
+			if other.parents[n] >= 0 {
+				if meetA < 0 || f.dist[idx] + other.dist[n] < best {
+					meetA, meetB, best = idx, n, f.dist[idx] + other.dist[n]
+				}
+				continue
+			}
+			if f.parents[n] < 0 && !f.g.exits[n] {
+				f.add(n, idx)
+			}
+		}
+	}
+	return meetA, meetB
+}
+
+// IDAStarPath returns the shortest path from the entrance to the exit using
+// iterative deepening A* search within the budget (the same heuristic as A*),
+// cells reached with longer paths are pruned by the transposition table
+// complexity: x * y * iterations, memory: x * y
+func IDAStarPath(m *models.Maze, budget Budget) ([]string, bool, error) {
+	g := newGrid(m)
+	start := parseCell(m.Entrance)
+	from := g.index(start.x, start.y)
+
+	s := &idaSearch{
+		g: g,
+		budget: budget,
+		visited: make([]bool, len(g.walls)),
+		path: []int{from},
+		dist: g.exitDistances(),
+	}
+	if budget.Timeout > 0 {
+		s.deadline = time.Now().Add(budget.Timeout)
+	}
+	s.visited[from] = true
+
+	threshold := s.estimate(from, 0)
+	for {
+		s.reached = make([]int, len(g.walls))
+		next := s.search(0, threshold)
+		if next == 0 {
+			path := make([]string, len(s.path))
+			for i, idx := range s.path {
+				path[i] = encodeCell(g.cell(idx))
+			}
+			return path, true, nil
+		}
+		if s.exhausted {
+			return nil, false, fmt.Errorf("Search budget exhausted")
+		}
+		if next < 0 {
+			return nil, false, fmt.Errorf("Maze doesn't have a solution")
+		}
+		threshold = next
+	}
+}
+
+type idaSearch struct {
+	g         *grid
+	budget    Budget
+	deadline  time.Time
+	nodes     int
+	exhausted bool
+	visited   []bool
+	path      []int
+	// min steps the cell was reached with in the current iteration (transposition table)
+	reached   []int
+	// heuristic distances to the nearest exit
+	dist      []int
+}
+
+// search returns 0 when the exit is found, otherwise the min estimation
+// exceeding the threshold (-1 if there are no more cells to explore)
+func (s *idaSearch) search(steps, threshold int) int {
+	s.nodes++
+	if (s.budget.Nodes > 0 && s.nodes > s.budget.Nodes) ||
+		(!s.deadline.IsZero() && s.nodes % 1024 == 0 && time.Now().After(s.deadline)) {
+		s.exhausted = true
+		return -1
+	}
+
+	idx := s.path[len(s.path) - 1]
+	if f := s.estimate(idx, steps); f > threshold {
+		return f
+	}
+	if s.reached[idx] > 0 && s.reached[idx] <= steps + 1 {
+		// already explored with the same or shorter path in this iteration
+		return -1
+	}
+	s.reached[idx] = steps + 1
+	if s.g.exits[idx] {
+		return 0
+	}
+
+	min := -1
+	for _, n := range s.g.neighbours(idx) {
+		if s.g.walls[n] || s.visited[n] {
+			continue
+		}
+
+		s.visited[n] = true
+		s.path = append(s.path, n)
+		res := s.search(steps + 1, threshold)
+		if res == 0 {
+			return 0
+		}
+		s.path = s.path[:len(s.path) - 1]
+		s.visited[n] = false
+
+		if s.exhausted {
+			return -1
+		}
+		if res > 0 && (min < 0 || res < min) {
+			min = res
+		}
+	}
+	return min
+}
+
+func (s *idaSearch) estimate(idx, steps int) int {
+	return steps + s.dist[idx]
+}
+
+// traceCell traces back and reverses result path
+func traceCell(solution *cell) []string {
+	var res []string
+	for solution != nil {
+		res = append(res, encodeCell(*solution))
+		solution = solution.parent
+	}
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
+
+type cellHeap struct {
+	items    []*cell
+	estimate func(c *cell) int
+	tracer   Tracer
+}
+func (h *cellHeap) Len() int {
+	return len(h.items)
+}
+func (h *cellHeap) Less(i, j int) bool {
+	fi, fj := h.estimate(h.items[i]), h.estimate(h.items[j])
+	if fi == fj {
+		// cells with the same estimation are sorted by their path steps count (closer to exit first)
+		return h.items[i].steps > h.items[j].steps
+	}
+	return fi < fj
+}
+func (h cellHeap) Swap(i, j int){
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+func (h *cellHeap) Push(x any) {
+	h.items = append(h.items, x.(*cell))
+	trace(h.tracer, TracePush, *x.(*cell))
+}
+func (h *cellHeap) Pop() any {
+	n := len(h.items)
+	x := h.items[n - 1]
+	h.items[n - 1] = nil
+	h.items = h.items[: n - 1]
+	trace(h.tracer, TracePop, *x)
+	return x
+}
+
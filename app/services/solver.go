@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Solution steps goals
+const (
+	MinSteps = "min"
+	MaxSteps = "max"
+)
+
+// Default algorithms used for precalculated maze solutions
+const (
+	DefaultMinAlgorithm = "astar"
+	DefaultMaxAlgorithm = "longest"
+)
+
+// Solver searches for a maze solution path
+type Solver interface {
+	// Solve returns solution path and whether it is proven optimal
+	Solve(m *models.Maze) ([]string, bool, error)
+}
+
+// SolverFunc is an adapter to use ordinary functions as solvers
+type SolverFunc func(m *models.Maze) ([]string, bool, error)
+
+// Solve calls f(m)
+func (f SolverFunc) Solve(m *models.Maze) ([]string, bool, error) {
+	return f(m)
+}
+
+// registered solvers by steps goal and algorithm name
+var solvers = map[string]map[string]Solver{
+	MinSteps: {},
+	MaxSteps: {},
+}
+
+func init() {
+	RegisterSolver(MinSteps, "bfs", exact(BFSPath))
+	RegisterSolver(MinSteps, "astar", exact(AStarPath))
+	RegisterSolver(MinSteps, "dijkstra", exact(DijkstraPath))
+	RegisterSolver(MinSteps, "bidirectional", exact(BidirectionalPath))
+	RegisterSolver(MinSteps, "idastar", SolverFunc(func(m *models.Maze) ([]string, bool, error) {
+		return IDAStarPath(m, DefaultBudget)
+	}))
+	RegisterSolver(MinSteps, "dfs", SolverFunc(DFSPath))
+	RegisterSolver(MaxSteps, "dfs", SolverFunc(DFSPath))
+	RegisterSolver(MaxSteps, "longest", SolverFunc(func(m *models.Maze) ([]string, bool, error) {
+		return LongestPath(m, DefaultBudget)
+	}))
+}
+
+// RegisterSolver makes the solver available by algorithm name for the steps goal
+func RegisterSolver(steps, algorithm string, s Solver) {
+	solvers[steps][algorithm] = s
+}
+
+// FindSolver returns registered solver for the steps goal (nil if not found)
+func FindSolver(steps, algorithm string) Solver {
+	return solvers[steps][algorithm]
+}
+
+// Algorithms returns sorted names of the solvers registered for the steps goal
+func Algorithms(steps string) []string {
+	var res []string
+	for name := range solvers[steps] {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// DFSPath returns the first path to the exit found by depth-first search,
+// the path is not proven to be neither the shortest nor the longest one
+// complexity: x * y
+func DFSPath(m *models.Maze) ([]string, bool, error) {
+	g := newGrid(m)
+	start := parseCell(m.Entrance)
+	from := g.index(start.x, start.y)
+
+	parents := make([]int, len(g.walls))
+	for i := range parents {
+		parents[i] = -1
+	}
+	parents[from] = from
+
+	stack := []int{from}
+	for len(stack) > 0 {
+		next := stack[len(stack) - 1]
+		stack = stack[:len(stack) - 1]
+		if g.exits[next] {
+			return g.trace(parents, next), false, nil
+		}
+
+		for _, n := range g.neighbours(next) {
+			if g.walls[n] || parents[n] >= 0 {
+				continue
+			}
+			parents[n] = next
+			stack = append(stack, n)
+		}
+	}
+	return nil, false, fmt.Errorf("Maze doesn't have a solution")
+}
+
+// exact adapts the shortest path function to the Solver interface
+func exact(solve func(m *models.Maze) ([]string, error)) Solver {
+	return SolverFunc(func(m *models.Maze) ([]string, bool, error) {
+		path, err := solve(m)
+		return path, err == nil, err
+	})
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"github.com/mkulish/mazes/app/models"
+)
+
+// Trace event kinds
+const (
+	// cell is added to the search frontier (heap push or depth-first step forward)
+	TracePush = "push"
+	// cell is taken from the search frontier (heap pop or depth-first step back)
+	TracePop = "pop"
+)
+
+// DefaultTraceLimit is the max number of recorded trace events
+const DefaultTraceLimit = 20000
+
+// Tracer receives solver search events, used for the search visualization
+type Tracer interface {
+	Trace(event models.MazeTraceEvent)
+}
+
+// TraceRecorder records search events up to the limit
+type TraceRecorder struct {
+	// Limit is the max number of recorded events (0 - unlimited)
+	Limit int
+	Events []models.MazeTraceEvent
+	// Truncated is set when events over the limit were dropped
+	Truncated bool
+}
+
+// Trace records the event
+func (r *TraceRecorder) Trace(event models.MazeTraceEvent) {
+	if r.Limit > 0 && len(r.Events) >= r.Limit {
+		r.Truncated = true
+		return
+	}
+	r.Events = append(r.Events, event)
+}
+
+// TraceMaze solves the maze with the default min/max steps algorithm like SolveMaze
+// reporting the search events to the tracer
+func TraceMaze(m *models.Maze, min bool, tracer Tracer) ([]string, bool, error) {
+	if err := CheckExits(m); err != nil {
+		return nil, false, err
+	}
+	if min {
+		path, err := bestFirstPath(m, true, tracer)
+		return path, err == nil, err
+	}
+	return longestPath(m, DefaultBudget, tracer)
+}
+
+// trace reports the cell event to the tracer (if any)
+func trace(tracer Tracer, kind string, c cell) {
+	if tracer != nil {
+		tracer.Trace(models.MazeTraceEvent{Kind: kind, Cell: encodeCell(c), Steps: c.steps})
+	}
+}
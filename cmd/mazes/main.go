@@ -0,0 +1,99 @@
+// Command mazes provides maintenance subcommands of the mazes app:
+//
+//     mazes migrate [-db dsn] status
+//     mazes migrate [-db dsn] up [version]
+//     mazes migrate [-db dsn] down [steps]
+//
+// The database is sqlite3 data source name (MAZES_DB by default), e.g. file:mazes.db
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mkulish/mazes/app/migrations"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "migrate" {
+		fmt.Fprintln(os.Stderr, "Usage: mazes migrate [-db dsn] status|up [version]|down [steps]")
+		os.Exit(2)
+	}
+	if err := migrate(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// migrate runs migrate subcommand
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dsn := flags.String("db", os.Getenv("MAZES_DB"), "sqlite3 data source name")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: mazes migrate [-db dsn] status|up [version]|down [steps]")
+		fmt.Fprintln(flags.Output(), "  status - current and latest schema versions")
+		fmt.Fprintln(flags.Output(), "  up     - apply migrations up to the version (the latest by default)")
+		fmt.Fprintln(flags.Output(), "  down   - roll back the latest applied migrations (1 by default)")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *dsn == "" || flags.NArg() == 0 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command, n := flags.Arg(0), 0
+	if flags.NArg() == 2 {
+		var err error
+		if n, err = strconv.Atoi(flags.Arg(1)); err != nil {
+			return fmt.Errorf("Incorrect %s argument: %s", command, flags.Arg(1))
+		}
+	}
+
+	db, err := sql.Open("sqlite3", *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "status":
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Schema version: %d, latest: %d\n", version, migrator.Latest())
+		return migrator.Check()
+
+	case "up":
+		applied, err := migrator.Up(n)
+		for _, migration := range applied {
+			fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "down":
+		if flags.NArg() == 1 {
+			n = 1
+		}
+		rolledBack, err := migrator.Down(n)
+		for _, migration := range rolledBack {
+			fmt.Printf("Rolled back %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	}
+
+	flags.Usage()
+	os.Exit(2)
+	return nil
+}
@@ -0,0 +1,488 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	rgorp "github.com/revel/modules/orm/gorp/app"
+	"github.com/revel/revel/testing"
+
+	"github.com/mkulish/mazes/app/auth"
+	"github.com/mkulish/mazes/app/models"
+)
+
+// AuthTest contains integration tests for auth tokens
+type AuthTest struct {
+	testing.TestSuite
+}
+
+// TestTokenShouldRotateKeys ...
+func (t *AuthTest) TestTokenShouldRotateKeys() {
+	defaultKeyring := auth.DefaultKeyring
+	defer func() {
+		auth.DefaultKeyring = defaultKeyring
+	}()
+
+	auth.DefaultKeyring = auth.NewKeyring()
+	auth.DefaultKeyring.Add(&auth.Key{ID: "k1", Method: jwt.SigningMethodHS256, SignKey: []byte("s1"), VerifyKey: []byte("s1")})
+	oldToken := t.login()
+
+	// token should have kid header
+	token, _, err := new(jwt.Parser).ParseUnverified(oldToken, jwt.MapClaims{})
+	t.AssertEqual(err, nil)
+	t.AssertEqual(token.Header["kid"], "k1")
+
+	// should accept tokens of all active keys
+	auth.DefaultKeyring.Add(&auth.Key{ID: "k2", Method: jwt.SigningMethodHS256, SignKey: []byte("s2"), VerifyKey: []byte("s2")})
+	auth.DefaultKeyring.Current = "k2"
+	newToken := t.login()
+	t.authGet(oldToken)
+	t.AssertOk()
+	t.authGet(newToken)
+	t.AssertOk()
+
+	// should reject tokens of retired keys
+	auth.DefaultKeyring.Retire("k1")
+	t.authGet(oldToken)
+	t.AssertStatus(401)
+	t.authGet(newToken)
+	t.AssertOk()
+
+	// should reject tokens without kid
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "authtest"})
+	tokenString, _ := unsigned.SignedString([]byte("s2"))
+	t.authGet(tokenString)
+	t.AssertStatus(401)
+}
+
+// TestRefreshShouldRotateTokens ...
+func (t *AuthTest) TestRefreshShouldRotateTokens() {
+	login := t.loginResponse()
+	t.Assert(login.RefreshToken != "")
+	t.Assert(login.ExpiresIn > 0)
+
+	// should issue new tokens
+	refreshed := t.refresh(login.RefreshToken)
+	t.AssertOk()
+	t.Assert(refreshed.RefreshToken != login.RefreshToken)
+	t.authGet(refreshed.Token)
+	t.AssertOk()
+
+	// reuse of the rotated token should revoke the whole login
+	t.refresh(login.RefreshToken)
+	t.AssertStatus(401)
+	t.refresh(refreshed.RefreshToken)
+	t.AssertStatus(401)
+
+	t.refresh("unknown")
+	t.AssertStatus(401)
+	t.Post("/token/refresh", "application/json", strings.NewReader("{}"))
+	t.AssertStatus(400)
+}
+
+// TestLogoutShouldRevokeTokens ...
+func (t *AuthTest) TestLogoutShouldRevokeTokens() {
+	login := t.loginResponse()
+	other := t.login()
+
+	req := t.PostCustom(t.BaseUrl() + "/logout", "application/json",
+		strings.NewReader("{\"refreshToken\": \"" + login.RefreshToken + "\"}"))
+	req.Header.Add("Authorization", "Bearer " + login.Token)
+	req.Send()
+	t.AssertOk()
+
+	// should revoke the auth token and the refresh token
+	t.authGet(login.Token)
+	t.AssertStatus(401)
+	t.refresh(login.RefreshToken)
+	t.AssertStatus(401)
+
+	// other logins should be valid
+	t.authGet(other)
+	t.AssertOk()
+
+	t.Post("/logout", "application/json", nil)
+	t.AssertStatus(401)
+}
+
+// TestAccessTokenShouldEnforceScope ...
+func (t *AuthTest) TestAccessTokenShouldEnforceScope() {
+	token := t.login()
+
+	// should validate scope
+	t.send("POST", "/user/tokens", token, "{\"name\": \"ci\", \"scope\": \"admin\"}")
+	t.AssertStatus(400)
+	t.send("POST", "/user/tokens", token, "{\"name\": \"ci\"}")
+	t.AssertStatus(400)
+
+	t.send("POST", "/user/tokens", token, "{\"name\": \"ci\", \"scope\": \"read\"}")
+	t.AssertOk()
+	var created models.AccessTokenResponse
+	json.Unmarshal(t.ResponseBody, &created)
+	t.AssertEqual(created.Item.Scope, "read")
+
+	// read-only token should not write
+	t.authGet(created.Token)
+	t.AssertOk()
+	t.send("POST", "/maze", created.Token, "{}")
+	t.AssertStatus(403)
+	t.AssertContentType("application/json; charset=utf-8")
+	var forbidden models.ForbiddenError
+	json.Unmarshal(t.ResponseBody, &forbidden)
+	t.AssertEqual(forbidden.Scope, "write")
+	t.send("POST", "/user/tokens", created.Token, "{\"name\": \"ci\", \"scope\": \"read write\"}")
+	t.AssertStatus(403)
+
+	// should list and revoke tokens
+	t.send("GET", "/user/tokens", created.Token, "")
+	t.AssertOk()
+	var list models.AccessTokenListResponse
+	json.Unmarshal(t.ResponseBody, &list)
+	t.Assert(len(list.Items) > 0)
+
+	t.send("DELETE", fmt.Sprintf("/user/tokens/%d", created.Item.ID), token, "")
+	t.AssertOk()
+	t.authGet(created.Token)
+	t.AssertStatus(401)
+}
+
+// TestAPIKeyShouldAuthenticate ...
+func (t *AuthTest) TestAPIKeyShouldAuthenticate() {
+	token := t.login()
+
+	t.send("POST", "/user/keys", token, "{\"label\": \"ci\"}")
+	t.AssertStatus(400)
+	t.send("POST", "/user/keys", token, "{\"label\": \"ci\", \"scope\": \"read\"}")
+	t.AssertOk()
+	var created models.APIKeyResponse
+	json.Unmarshal(t.ResponseBody, &created)
+	t.Assert(strings.HasPrefix(created.Key, created.Item.Prefix))
+
+	// should accept both headers, read scope only
+	req := t.GetCustom(t.BaseUrl() + "/maze")
+	req.Header.Add("X-API-Key", created.Key)
+	req.Send()
+	t.AssertOk()
+	req = t.GetCustom(t.BaseUrl() + "/maze")
+	req.Header.Add("Authorization", "ApiKey " + created.Key)
+	req.Send()
+	t.AssertOk()
+	req = t.PostCustom(t.BaseUrl() + "/maze", "application/json", strings.NewReader("{}"))
+	req.Header.Add("X-API-Key", created.Key)
+	req.Send()
+	t.AssertStatus(403)
+
+	// should label and track usage
+	path := fmt.Sprintf("/user/keys/%d", created.Item.ID)
+	t.send("PATCH", path, token, "{\"label\": \"dashboard\"}")
+	t.AssertOk()
+	t.send("GET", fmt.Sprintf("/user/me/audit?action=%s&targetId=%d", models.AuditAPIKeyUpdate, created.Item.ID), token, "")
+	t.AssertOk()
+	var events models.AuditListResponse
+	json.Unmarshal(t.ResponseBody, &events)
+	t.AssertEqual(len(events.Items), 1)
+	t.AssertEqual(events.Items[0].Diff["label"].Old, "ci")
+	t.AssertEqual(events.Items[0].Diff["label"].New, "dashboard")
+	t.send("GET", "/user/keys", token, "")
+	t.AssertOk()
+	var list models.APIKeyListResponse
+	json.Unmarshal(t.ResponseBody, &list)
+	for _, key := range list.Items {
+		if key.ID == created.Item.ID {
+			t.AssertEqual(key.Label, "dashboard")
+			t.Assert(key.LastUsed > 0)
+		}
+	}
+
+	// should revoke
+	t.send("DELETE", path, token, "")
+	t.AssertOk()
+	req = t.GetCustom(t.BaseUrl() + "/maze")
+	req.Header.Add("X-API-Key", created.Key)
+	req.Send()
+	t.AssertStatus(401)
+}
+
+// TestAccountShouldChangePasswordAndDelete ...
+func (t *AuthTest) TestAccountShouldChangePasswordAndDelete() {
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"account\", \"password\": \"12345\"}"))
+	t.AssertOk()
+	var login models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &login)
+
+	t.send("GET", "/user/me", login.Token, "")
+	t.AssertOk()
+	var profile models.UserResponse
+	json.Unmarshal(t.ResponseBody, &profile)
+	t.AssertEqual(profile.Username, "account")
+	t.AssertEqual(profile.Mazes, int64(0))
+
+	// should check the old password
+	t.send("PUT", "/user/me/password", login.Token, "{\"oldPassword\": \"54321\", \"newPassword\": \"123456\"}")
+	t.AssertStatus(400)
+	t.send("PUT", "/user/me/password", login.Token, "{\"oldPassword\": \"12345\", \"newPassword\": \"123\"}")
+	t.AssertStatus(400)
+
+	// should revoke existing tokens
+	t.send("PUT", "/user/me/password", login.Token, "{\"oldPassword\": \"12345\", \"newPassword\": \"123456\"}")
+	t.AssertOk()
+	var changed models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &changed)
+	t.authGet(login.Token)
+	t.AssertStatus(401)
+	t.refresh(login.RefreshToken)
+	t.AssertStatus(401)
+	t.authGet(changed.Token)
+	t.AssertOk()
+
+	// revoked refresh tokens should not be reported as reuse, the new token family should stay valid
+	t.send("GET", "/user/me/audit?action=" + models.AuditTokenReuse, changed.Token, "")
+	t.AssertOk()
+	var events models.AuditListResponse
+	json.Unmarshal(t.ResponseBody, &events)
+	t.AssertEqual(len(events.Items), 0)
+	t.refresh(changed.RefreshToken)
+	t.AssertOk()
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"account\", \"password\": \"123456\"}"))
+	t.AssertOk()
+
+	// should delete the account with confirmation
+	t.send("DELETE", "/user/me", changed.Token, "{\"password\": \"12345\"}")
+	t.AssertStatus(400)
+	t.send("DELETE", "/user/me", changed.Token, "{\"password\": \"123456\"}")
+	t.AssertOk()
+	t.authGet(changed.Token)
+	t.AssertStatus(401)
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"account\", \"password\": \"123456\"}"))
+	t.AssertStatus(400)
+}
+
+// TestLoginShouldThrottleAttempts ...
+func (t *AuthTest) TestLoginShouldThrottleAttempts() {
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"throttle\", \"password\": \"12345\"}"))
+	t.AssertOk()
+
+	// unknown usernames should get the same error
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"unknown\", \"password\": \"12345\"}"))
+	t.AssertStatus(400)
+	unknown := string(t.ResponseBody)
+
+	for i := 0; i <= auth.UsernameThrottle.Free; i++ {
+		t.Post("/login", "application/json", strings.NewReader("{\"username\": \"throttle\", \"password\": \"54321\"}"))
+		t.AssertStatus(400)
+		t.AssertEqual(string(t.ResponseBody), unknown)
+	}
+
+	// should delay the next attempt even with the correct password
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"throttle\", \"password\": \"12345\"}"))
+	t.AssertStatus(429)
+	t.AssertEqual(t.Response.Header.Get("Retry-After"), "1")
+	var resp models.TooManyRequestsError
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.RetryAfter, int64(1))
+}
+
+// TestLoginShouldThrottleConcurrentAttempts ...
+func (t *AuthTest) TestLoginShouldThrottleConcurrentAttempts() {
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"burst\", \"password\": \"12345\"}"))
+	t.AssertOk()
+
+	// a burst of parallel attempts should get no more password checks than the free ones
+	const burst = 10
+	statuses := make(chan int, burst)
+	var wg sync.WaitGroup
+	for i := 0; i < burst; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Post(t.BaseUrl() + "/login", "application/json",
+				strings.NewReader("{\"username\": \"burst\", \"password\": \"54321\"}"))
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	t.AssertEqual(counts[400], auth.UsernameThrottle.Free + 1)
+	t.AssertEqual(counts[429], burst - auth.UsernameThrottle.Free - 1)
+}
+
+// TestAdminShouldModerateUsers ...
+func (t *AuthTest) TestAdminShouldModerateUsers() {
+	admin := t.adminLogin()
+
+	// should never grant the admin role on registration
+	models.AdminUsernames["squatter"] = true
+	defer delete(models.AdminUsernames, "squatter")
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"squatter\", \"password\": \"12345\"}"))
+	t.AssertOk()
+	var squatter models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &squatter)
+	t.send("GET", "/admin/users", squatter.Token, "")
+	t.AssertStatus(403)
+
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"abuser\", \"password\": \"12345\"}"))
+	t.AssertOk()
+	var login models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &login)
+	t.send("POST", "/maze/generate", login.Token, "{\"entrance\": \"A1\", \"gridSize\": \"5x5\"}")
+	t.AssertOk()
+	var maze models.MazeResponse
+	json.Unmarshal(t.ResponseBody, &maze)
+
+	// should require the admin role
+	t.send("GET", "/admin/users", login.Token, "")
+	t.AssertStatus(403)
+
+	t.send("GET", "/admin/users?q=abuse", admin.Token, "")
+	t.AssertOk()
+	var users models.AdminUserListResponse
+	json.Unmarshal(t.ResponseBody, &users)
+	t.AssertEqual(len(users.Items), 1)
+	t.AssertEqual(users.Items[0].Mazes, int64(1))
+	userPath := fmt.Sprintf("/admin/users/%d", users.Items[0].ID)
+
+	// should find and delete mazes of any user
+	t.send("GET", fmt.Sprintf("/admin/mazes?owner=%d", users.Items[0].ID), admin.Token, "")
+	t.AssertOk()
+	var mazes models.AdminMazeSearchResponse
+	json.Unmarshal(t.ResponseBody, &mazes)
+	t.AssertEqual(len(mazes.Items), 1)
+	t.AssertEqual(mazes.Items[0].OwnerID, users.Items[0].ID)
+	t.send("DELETE", fmt.Sprintf("/admin/mazes/%d", maze.ID), admin.Token, "")
+	t.AssertOk()
+	t.send("GET", fmt.Sprintf("/maze/%d", maze.ID), login.Token, "")
+	t.AssertStatus(400)
+
+	// disabled users should not login
+	t.send("POST", userPath + "/disable", admin.Token, "")
+	t.AssertOk()
+	t.authGet(login.Token)
+	t.AssertStatus(401)
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"abuser\", \"password\": \"12345\"}"))
+	t.AssertStatus(403)
+
+	// should reset the password and enable the user
+	t.send("PUT", userPath + "/password", admin.Token, "{\"newPassword\": \"54321\"}")
+	t.AssertOk()
+	t.send("POST", userPath + "/enable", admin.Token, "")
+	t.AssertOk()
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"abuser\", \"password\": \"54321\"}"))
+	t.AssertOk()
+}
+
+// TestAuditShouldRecordEvents ...
+func (t *AuthTest) TestAuditShouldRecordEvents() {
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"audited\", \"password\": \"12345\"}"))
+	t.AssertOk()
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"audited\", \"password\": \"54321\"}"))
+	t.AssertStatus(400)
+	t.Post("/login", "application/json", strings.NewReader("{\"username\": \"audited\", \"password\": \"12345\"}"))
+	t.AssertOk()
+	var login models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &login)
+
+	t.send("POST", "/maze/generate", login.Token, "{\"entrance\": \"A1\", \"gridSize\": \"5x5\"}")
+	t.AssertOk()
+	var maze models.MazeResponse
+	json.Unmarshal(t.ResponseBody, &maze)
+	t.send("PATCH", fmt.Sprintf("/maze/%d", maze.ID), login.Token, "{\"visibility\": \"public\"}")
+	t.AssertOk()
+
+	// should return own events and events targeting the account, newest first
+	t.send("GET", "/user/me/audit", login.Token, "")
+	t.AssertOk()
+	var events models.AuditListResponse
+	json.Unmarshal(t.ResponseBody, &events)
+	actions := []string{}
+	for _, event := range events.Items {
+		actions = append(actions, event.Action)
+	}
+	t.AssertEqual(actions, []string{
+		models.AuditMazeUpdate,
+		models.AuditMazeCreate,
+		models.AuditLogin,
+		models.AuditLoginFailure,
+		models.AuditRegister,
+	})
+	t.AssertEqual(events.Items[0].Diff["visibility"].New, models.VisibilityPublic)
+	t.AssertEqual(events.Items[3].ActorID, int64(0))
+	t.AssertEqual(events.Items[3].TargetID, events.Items[4].ActorID)
+
+	// should require the admin role for all users events
+	t.send("GET", "/audit", login.Token, "")
+	t.AssertStatus(403)
+	admin := t.adminLogin()
+	t.send("GET", fmt.Sprintf("/audit?actor=%d&action=maze", events.Items[4].ActorID), admin.Token, "")
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &events)
+	t.AssertEqual(len(events.Items), 2)
+}
+
+// adminLogin returns login response of the admin user, the role is synced
+// as on startup with the configured admin usernames
+func (t *AuthTest) adminLogin() models.LoginResponse {
+	credentials := "{\"username\": \"admin\", \"password\": \"12345\"}"
+	t.Post("/user", "application/json", strings.NewReader(credentials))
+	if t.Response.StatusCode != 200 {
+		t.Post("/login", "application/json", strings.NewReader(credentials))
+	}
+	t.AssertOk()
+	var admin models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &admin)
+
+	_, _, err := models.SyncAdminRoles(rgorp.Db.Map, map[string]bool{"admin": true})
+	t.Assert(err == nil)
+	return admin
+}
+
+func (t *AuthTest) login() string {
+	return t.loginResponse().Token
+}
+
+func (t *AuthTest) loginResponse() models.LoginResponse {
+	credentials := "{\"username\": \"authtest\", \"password\": \"12345\"}"
+	t.Post("/user", "application/json", strings.NewReader(credentials))
+	if t.Response.StatusCode != 200 {
+		t.Post("/login", "application/json", strings.NewReader(credentials))
+	}
+	t.AssertOk()
+
+	var resp models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &resp)
+	return resp
+}
+
+func (t *AuthTest) refresh(refreshToken string) models.LoginResponse {
+	t.Post("/token/refresh", "application/json", strings.NewReader("{\"refreshToken\": \"" + refreshToken + "\"}"))
+
+	var resp models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &resp)
+	return resp
+}
+
+func (t *AuthTest) send(method, path, token, body string) {
+	req, _ := http.NewRequest(method, t.BaseUrl() + path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer " + token)
+	t.NewTestRequest(req).Send()
+}
+
+func (t *AuthTest) authGet(token string) {
+	req := t.GetCustom(t.BaseUrl() + "/maze")
+	req.Header.Add("Authorization", "Bearer " + token)
+	req.Send()
+}
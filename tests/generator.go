@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/revel/revel"
+	"github.com/revel/revel/testing"
+
+	"github.com/mkulish/mazes/app/generator"
+	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/services"
+)
+
+// number of random grids checked for each generation algorithm
+const generatorSamples = 100
+
+// GeneratorTest contains property-based tests for maze generators
+type GeneratorTest struct {
+	testing.TestSuite
+}
+
+// TestGenerateShouldProducePerfectMaze ...
+func (t *GeneratorTest) TestGenerateShouldProducePerfectMaze() {
+	rnd := rand.New(rand.NewSource(solverSeed))
+	for _, name := range generator.Algorithms() {
+		for i := 0; i < generatorSamples; i++ {
+			rows, cols := 3 + rnd.Intn(20), 2 + rnd.Intn(20)
+			maze := models.Maze{
+				Entrance: services.CellName(rnd.Intn(cols), rnd.Intn(rows - 1)),
+				GridSize: fmt.Sprintf("%dx%d", rows, cols),
+			}
+			err := generator.Generate(&maze, name, rnd.Int63())
+			t.Assertf(err == nil, "%s: unexpected error for %v: %v", name, maze, err)
+
+			v := &revel.Validation{}
+			services.ValidateMazeGrid(&maze, v)
+			t.Assertf(!v.HasErrors(), "%s: invalid maze %v: %v", name, maze, v.Errors)
+
+			// all open cells are connected and the only path is both the shortest and the longest one
+			reach := services.AnalyzeReachability(&maze)
+			t.Assertf(reach.Cells + len(reach.Exits) == rows * cols - len(maze.Walls), "%s: unreachable cells in %v", name, maze)
+			min, _, minErr := services.SolveMaze(&maze, true)
+			max, _, maxErr := services.SolveMaze(&maze, false)
+			t.Assertf(minErr == nil && maxErr == nil, "%s: no solution for %v", name, maze)
+			t.Assertf(len(min) == len(max), "%s: multiple paths in %v", name, maze)
+		}
+	}
+}
+
+// TestGenerateShouldBeReproducible ...
+func (t *GeneratorTest) TestGenerateShouldBeReproducible() {
+	for _, name := range generator.Algorithms() {
+		first := models.Maze{Entrance: "B2", GridSize: "15x15"}
+		second := first
+		generator.Generate(&first, name, solverSeed)
+		generator.Generate(&second, name, solverSeed)
+		t.AssertEqual(first.Walls, second.Walls)
+	}
+}
+
+// TestGenerateConstrainedShouldMeasureExactMaxPath ...
+func (t *GeneratorTest) TestGenerateConstrainedShouldMeasureExactMaxPath() {
+	// braided (non-perfect) mazes with the longest path constraint
+	constraints := models.MazeConstraints{DeadEndsTo: 3, MaxPathFrom: 30, MaxPathTo: 60}
+	for _, name := range generator.Algorithms() {
+		maze := models.Maze{Entrance: "A1", GridSize: "10x10"}
+		res, err := generator.GenerateConstrained(&maze, name, solverSeed, constraints, generator.Budget{Attempts: 200})
+		t.Assertf(err == nil, "%s: unexpected error: %v", name, err)
+		t.Assertf(res.MaxPathExact, "%s: max path is not exact", name)
+
+		path, optimal, err := services.LongestPath(&maze, services.Budget{})
+		t.Assert(err == nil && optimal)
+		t.AssertEqual(res.Metrics.MaxPath, len(path) - 1)
+		t.Assertf(res.Metrics.MaxPath >= constraints.MaxPathFrom && res.Metrics.MaxPath <= constraints.MaxPathTo,
+			"%s: max path %d is out of range", name, res.Metrics.MaxPath)
+	}
+}
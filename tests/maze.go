@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"net/http"
 	"strings"
 
+	rgorp "github.com/revel/modules/orm/gorp/app"
 	"github.com/revel/revel/testing"
 
+	"github.com/mkulish/mazes/app/generator"
 	"github.com/mkulish/mazes/app/models"
 )
 
@@ -89,6 +93,62 @@ func (t *MazeTest) TestCreateShouldValidateGrid() {
 	t.AssertStatus(400)
 }
 
+// TestCreateShouldSupportMultiLetterColumns ...
+func (t *MazeTest) TestCreateShouldSupportMultiLetterColumns() {
+	wideMaze := models.Maze{Entrance: "AA1", GridSize: "2x27"}
+	for col := 'A'; col <= 'Z'; col++ {
+		wideMaze.Walls = append(wideMaze.Walls, fmt.Sprintf("%c2", col))
+	}
+
+	t.postObject(t.BaseUrl()+"/maze", wideMaze)
+	t.AssertOk()
+
+	var mazeResp models.MazeResponse
+	json.Unmarshal(t.ResponseBody, &mazeResp)
+
+	var resp models.MazeSolutionResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d/solution?steps=min", t.BaseUrl(), mazeResp.ID))
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Path, []string{"AA1", "AA2"})
+
+	// should check max grid size
+	wideMaze.GridSize = "2x28"
+	t.postObject(t.BaseUrl()+"/maze", wideMaze)
+	t.AssertStatus(400)
+}
+
+// TestCreateShouldSupportExitPolicies ...
+func (t *MazeTest) TestCreateShouldSupportExitPolicies() {
+	exitMaze := validMazeWithSolution1
+	exitMaze.ExitPolicy = models.ExitCell
+	exitMaze.Exit = "C2"
+
+	t.postObject(t.BaseUrl()+"/maze", exitMaze)
+	t.AssertOk()
+
+	var mazeResp models.MazeResponse
+	json.Unmarshal(t.ResponseBody, &mazeResp)
+
+	var resp models.MazeSolutionResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d/solution?steps=min", t.BaseUrl(), mazeResp.ID))
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Path, []string{"A1", "B1", "C1", "C2"})
+
+	// should check exit cell
+	exitMaze.Exit = "B2"
+	t.postObject(t.BaseUrl()+"/maze", exitMaze)
+	t.AssertStatus(400)
+
+	exitMaze.Exit = ""
+	t.postObject(t.BaseUrl()+"/maze", exitMaze)
+	t.AssertStatus(400)
+
+	// should check exit policy
+	exitMaze.ExitPolicy = "top-edge"
+	t.postObject(t.BaseUrl()+"/maze", exitMaze)
+	t.AssertStatus(400)
+}
+
 // TestCreateShouldValidateWalls ...
 func (t *MazeTest) TestCreateShouldValidateWalls() {
 	invalidMaze := validMazeWithSolution1
@@ -107,6 +167,13 @@ func (t *MazeTest) TestCreateShouldValidateWalls() {
 	invalidMaze.Walls = []string{"A:2", "A200"}
 	t.postObject(t.BaseUrl()+"/maze", invalidMaze)
 	t.AssertStatus(400)
+
+	// signed and zero-padded rows
+	for _, wall := range []string{"A+2", "A02", "B-2"} {
+		invalidMaze.Walls = []string{wall}
+		t.postObject(t.BaseUrl()+"/maze", invalidMaze)
+		t.AssertStatus(400)
+	}
 }
 
 // TestCreateShouldValidateSolution ...
@@ -122,6 +189,10 @@ func (t *MazeTest) TestCreateShouldValidateSolution() {
 	invalidMaze.Walls = []string{"B2"}
 	t.postObject(t.BaseUrl()+"/maze", invalidMaze)
 	t.AssertStatus(400)
+
+	var resp models.ValidationError
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Errors[0].Message, "Maze has multiple exits: A4, B4, C4")
 }
 
 // TestSolutionShouldReturnUnauthorized ...
@@ -149,6 +220,13 @@ func (t *MazeTest) TestSolutionShouldReturnSolution() {
 	t.authGet(fmt.Sprintf("%s/maze/%d/solution?steps=max", t.BaseUrl(), id))
 	json.Unmarshal(t.ResponseBody, &resp)
 	t.AssertEqual(resp.Path, []string{"A1", "B1", "C1", "C2", "C3", "B3", "A3", "A4"})
+	t.Assert(resp.Optimal)
+
+	// should solve with the requested algorithm
+	t.authGet(fmt.Sprintf("%s/maze/%d/solution?steps=min&algorithm=bidirectional", t.BaseUrl(), id))
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Path, []string{"A1", "A2", "A3", "A4"})
+	t.AssertEqual(resp.Algorithm, "bidirectional")
 }
 
 // TestSolutionShouldValidateParams ...
@@ -169,6 +247,504 @@ func (t *MazeTest) TestSolutionShouldValidateParams() {
 
 	t.authGet(fmt.Sprintf("%s/maze/1/solution?steps=any", t.BaseUrl()))
 	t.AssertStatus(400)
+
+	// should check algorithm query param
+	t.authGet(fmt.Sprintf("%s/maze/1/solution?steps=max&algorithm=astar", t.BaseUrl()))
+	t.AssertStatus(400)
+}
+
+// TestGenerateShouldCreateSolvableMaze ...
+func (t *MazeTest) TestGenerateShouldCreateSolvableMaze() {
+	generation := models.MazeGeneration{Entrance: "A1", GridSize: "9x9", Seed: 42, Algorithm: "kruskal"}
+	t.postObject(t.BaseUrl() + "/maze/generate", generation)
+	t.AssertOk()
+
+	var resp models.MazeGenerationResponse
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Seed, int64(42))
+	t.AssertEqual(resp.Algorithm, "kruskal")
+
+	var solution models.MazeSolutionResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d/solution?steps=min", t.BaseUrl(), resp.ID))
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &solution)
+	t.AssertEqual(solution.Path[0], "A1")
+
+	// the same seed should produce the same maze
+	var first, second models.MazeItemResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d", t.BaseUrl(), resp.ID))
+	json.Unmarshal(t.ResponseBody, &first)
+	t.postObject(t.BaseUrl() + "/maze/generate", generation)
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.authGet(fmt.Sprintf("%s/maze/%d", t.BaseUrl(), resp.ID))
+	json.Unmarshal(t.ResponseBody, &second)
+	t.AssertEqual(first.Item.Walls, second.Item.Walls)
+}
+
+// TestGenerateShouldValidateParams ...
+func (t *MazeTest) TestGenerateShouldValidateParams() {
+	t.postObject(t.BaseUrl() + "/maze/generate", models.MazeGeneration{Entrance: "A1", GridSize: "9x9", Algorithm: "unknown"})
+	t.AssertStatus(400)
+
+	t.postObject(t.BaseUrl() + "/maze/generate", models.MazeGeneration{Entrance: "A9", GridSize: "9x9"})
+	t.AssertStatus(400)
+
+	t.postObject(t.BaseUrl() + "/maze/generate", models.MazeGeneration{Entrance: "A1", GridSize: "1x9"})
+	t.AssertStatus(400)
+
+	// single column grids should be rejected by any algorithm before generation
+	for _, algorithm := range generator.Algorithms() {
+		for _, gridSize := range []string{"2x1", "5x1", "8x1"} {
+			t.postObject(t.BaseUrl() + "/maze/generate", models.MazeGeneration{Entrance: "A1", GridSize: gridSize, Algorithm: algorithm})
+			t.AssertStatus(400)
+
+			var resp models.ValidationError
+			json.Unmarshal(t.ResponseBody, &resp)
+			t.AssertEqual(len(resp.Errors), 1)
+			t.AssertEqual(resp.Errors[0].Key, "gridSize")
+		}
+	}
+}
+
+// TestGenerateShouldMeetConstraints ...
+func (t *MazeTest) TestGenerateShouldMeetConstraints() {
+	constraints := &models.MazeConstraints{MinPathFrom: 60, DeadEndsFrom: 15}
+	t.postObject(t.BaseUrl() + "/maze/generate", models.MazeGeneration{
+		Entrance: "A1",
+		GridSize: "20x20",
+		Seed: 1,
+		Constraints: constraints,
+	})
+	t.AssertOk()
+
+	var resp models.MazeGenerationResponse
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.Assert(resp.Metrics != nil)
+	t.Assert(resp.Metrics.MinPath >= 60)
+	t.Assert(resp.Metrics.DeadEnds >= 15)
+	t.Assert(resp.Attempts > 0)
+
+	// unreachable constraints should report the closest miss
+	t.postObject(t.BaseUrl() + "/maze/generate", models.MazeGeneration{
+		Entrance: "A1",
+		GridSize: "9x9",
+		Attempts: 10,
+		Constraints: &models.MazeConstraints{MinPathFrom: 500},
+	})
+	t.AssertStatus(400)
+	t.AssertContains("Constraints not met in 10 attempts")
+}
+
+// TestShowShouldReturnMaze ...
+func (t *MazeTest) TestShowShouldReturnMaze() {
+	id := t.createMaze(validMazeWithSolution1)
+
+	t.authGet(fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id))
+	t.AssertOk()
+
+	var resp models.MazeItemResponse
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Item.ID, id)
+	t.AssertEqual(resp.Item.Walls, validMazeWithSolution1.Walls)
+
+	t.authGet(fmt.Sprintf("%s/maze/999", t.BaseUrl()))
+	t.AssertStatus(400)
+}
+
+// TestCreateShouldImportASCII ...
+func (t *MazeTest) TestCreateShouldImportASCII() {
+	req := t.PostCustom(t.BaseUrl() + "/maze", "text/plain", strings.NewReader("S..\n.#.\n...\n.##\n"))
+	req.Header.Add("Authorization", "Bearer " + validAuth)
+	req.Send()
+	t.AssertOk()
+
+	var resp models.MazeResponse
+	json.Unmarshal(t.ResponseBody, &resp)
+
+	var item models.MazeItemResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d", t.BaseUrl(), resp.ID))
+	json.Unmarshal(t.ResponseBody, &item)
+	t.AssertEqual(item.Item.Entrance, "A1")
+	t.AssertEqual(item.Item.GridSize, "4x3")
+	t.AssertEqual(item.Item.Walls, []string{"B2", "B4", "C4"})
+
+	req = t.PostCustom(t.BaseUrl() + "/maze", "text/plain", strings.NewReader("S.x\n"))
+	req.Header.Add("Authorization", "Bearer " + validAuth)
+	req.Send()
+	t.AssertStatus(400)
+}
+
+// TestShowShouldRenderASCII ...
+func (t *MazeTest) TestShowShouldRenderASCII() {
+	id := t.createMaze(validMazeWithSolution1)
+
+	req := t.GetCustom(fmt.Sprintf("%s/maze/%d?path=min", t.BaseUrl(), id))
+	req.Header.Add("Authorization", "Bearer " + validAuth)
+	req.Header.Set("Accept", "text/plain")
+	req.Send()
+	t.AssertOk()
+	t.AssertContentType("text/plain; charset=utf-8")
+	t.AssertEqual(string(t.ResponseBody), "S..\n*#.\n*..\n*##\n")
+}
+
+// TestRenderShouldDrawMaze ...
+func (t *MazeTest) TestRenderShouldDrawMaze() {
+	id := t.createMaze(validMazeWithSolution1)
+
+	t.authGet(fmt.Sprintf("%s/maze/%d/render.svg?path=min&labels=true&theme=dark", t.BaseUrl(), id))
+	t.AssertOk()
+	t.AssertContentType("image/svg+xml")
+	t.AssertContains("<svg")
+	t.AssertContains("<polyline")
+
+	t.authGet(fmt.Sprintf("%s/maze/%d/render.png?cellSize=10&wallColour=000000", t.BaseUrl(), id))
+	t.AssertOk()
+	t.AssertContentType("image/png")
+	t.Assert(bytes.HasPrefix(t.ResponseBody, []byte("\x89PNG")))
+
+	t.authGet(fmt.Sprintf("%s/maze/%d/render.png?cellSize=1000", t.BaseUrl(), id))
+	t.AssertStatus(400)
+	t.authGet(fmt.Sprintf("%s/maze/%d/render.svg?wallColour=red", t.BaseUrl(), id))
+	t.AssertStatus(400)
+}
+
+// TestTraceShouldRecordSearch ...
+func (t *MazeTest) TestTraceShouldRecordSearch() {
+	id := t.createMaze(validMazeWithSolution1)
+
+	var resp models.MazeTraceResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d/trace.json?steps=min", t.BaseUrl(), id))
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Algorithm, "astar")
+	t.AssertEqual(resp.Path, []string{"A1", "A2", "A3", "A4"})
+	t.AssertEqual(resp.Frames[0], models.MazeTraceEvent{Kind: "push", Cell: "A1", Steps: 0})
+	t.AssertEqual(resp.Frames[1], models.MazeTraceEvent{Kind: "pop", Cell: "A1", Steps: 0})
+
+	resp = models.MazeTraceResponse{}
+	t.authGet(fmt.Sprintf("%s/maze/%d/trace.json?steps=max", t.BaseUrl(), id))
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Algorithm, "longest")
+	t.Assert(len(resp.Frames) > 0)
+
+	t.authGet(fmt.Sprintf("%s/maze/%d/trace.gif?steps=max&frames=10", t.BaseUrl(), id))
+	t.AssertOk()
+	t.AssertContentType("image/gif")
+	t.Assert(bytes.HasPrefix(t.ResponseBody, []byte("GIF89a")))
+
+	t.authGet(fmt.Sprintf("%s/maze/%d/trace.gif?steps=all", t.BaseUrl(), id))
+	t.AssertStatus(400)
+}
+
+// TestUpdateShouldResolveMaze ...
+func (t *MazeTest) TestUpdateShouldResolveMaze() {
+	id := t.createMaze(validMazeWithSolution1)
+
+	updatedMaze := validMazeWithSolution1
+	updatedMaze.Walls = []string{"A2", "B2", "B4", "C4"}
+	t.sendObject("PUT", fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id), updatedMaze)
+	t.AssertOk()
+
+	var resp models.MazeSolutionResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d/solution?steps=min", t.BaseUrl(), id))
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Path, []string{"A1", "B1", "C1", "C2", "C3", "B3", "A3", "A4"})
+
+	// should validate updated maze
+	updatedMaze.Walls = []string{"B2"}
+	t.sendObject("PUT", fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id), updatedMaze)
+	t.AssertStatus(400)
+}
+
+// TestPatchShouldAddAndRemoveWalls ...
+func (t *MazeTest) TestPatchShouldAddAndRemoveWalls() {
+	id := t.createMaze(validMazeWithSolution1)
+
+	t.sendObject("PATCH", fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id), map[string]any{
+		"addWalls": []string{"A2"},
+	})
+	t.AssertOk()
+
+	var resp models.MazeItemResponse
+	t.authGet(fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id))
+	json.Unmarshal(t.ResponseBody, &resp)
+	// walls are returned in row-major order
+	t.AssertEqual(resp.Item.Walls, []string{"A2", "B2", "B4", "C4"})
+
+	t.sendObject("PATCH", fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id), map[string]any{
+		"removeWalls": []string{"A2"},
+		"entrance": "B1",
+	})
+	t.AssertOk()
+
+	t.authGet(fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id))
+	json.Unmarshal(t.ResponseBody, &resp)
+	t.AssertEqual(resp.Item.Walls, []string{"B2", "B4", "C4"})
+	t.AssertEqual(resp.Item.Entrance, "B1")
+}
+
+// TestWallsBitsetShouldRoundTrip ...
+func (t *MazeTest) TestWallsBitsetShouldRoundTrip() {
+	rnd := rand.New(rand.NewSource(solverSeed))
+	for i := 0; i < solverSamples; i++ {
+		rows, cols := 1 + rnd.Intn(99), 1 + rnd.Intn(60)
+
+		// random walls in row-major order
+		var walls []string
+		for y := 0; y < rows; y++ {
+			for x := 0; x < cols; x++ {
+				if rnd.Intn(3) == 0 {
+					walls = append(walls, models.CellName(x, y))
+				}
+			}
+		}
+
+		bits, err := models.EncodeWalls(walls, rows, cols)
+		t.Assertf(err == nil, "unexpected error for %dx%d: %v", rows, cols, err)
+		t.AssertEqual(len(bits), (rows * cols + 7) / 8)
+		t.AssertEqual(models.DecodeWalls(bits, rows, cols), walls)
+	}
+
+	_, err := models.EncodeWalls([]string{"D1"}, 4, 3)
+	t.Assert(err != nil)
+	_, err = models.EncodeWalls([]string{"A5"}, 4, 3)
+	t.Assert(err != nil)
+
+	// density of an empty grid
+	t.AssertEqual((&models.Maze{}).Density(), 0.0)
+	t.AssertEqual((&models.Maze{Rows: 4, Cols: 3, WallsCount: 6}).Density(), 0.5)
+}
+
+// TestDeleteShouldDeleteMaze ...
+func (t *MazeTest) TestDeleteShouldDeleteMaze() {
+	id := t.createMaze(validMazeWithSolution1)
+
+	t.sendObject("DELETE", fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id), nil)
+	t.AssertOk()
+
+	t.authGet(fmt.Sprintf("%s/maze/%d", t.BaseUrl(), id))
+	t.AssertStatus(400)
+}
+
+// TestSearchShouldPaginateAndSort ...
+func (t *MazeTest) TestSearchShouldPaginateAndSort() {
+	id := t.createMaze(models.Maze{
+		Entrance: "A1",
+		GridSize: "5x4",
+		Walls: []string{"B2", "B5", "C5", "D5"},
+	})
+
+	var first models.MazeSearchResponse
+	t.authGet(t.BaseUrl() + "/maze?sort=-size&limit=1&maxSize=5x4")
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &first)
+	t.AssertEqual(len(first.Items), 1)
+	t.AssertEqual(first.Items[0].ID, id)
+	t.Assert(first.NextCursor != "")
+
+	// the next page should continue the sort order
+	var next models.MazeSearchResponse
+	t.authGet(t.BaseUrl() + "/maze?sort=-size&limit=1&maxSize=5x4&cursor=" + first.NextCursor)
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &next)
+	for _, maze := range next.Items {
+		t.Assert(maze.ID != id)
+	}
+
+	t.authGet(t.BaseUrl() + "/maze?sort=walls")
+	t.AssertStatus(400)
+	t.authGet(t.BaseUrl() + "/maze?sort=id&cursor=" + first.NextCursor)
+	t.AssertStatus(400)
+}
+
+// TestSearchShouldFilterExactSolutions ...
+func (t *MazeTest) TestSearchShouldFilterExactSolutions() {
+	exact := t.createMaze(validMazeWithSolution1)
+	bestFound := t.createMaze(validMazeWithSolution1)
+	// longest path search budget exhausted
+	_, err := rgorp.Db.Map.Exec("UPDATE Maze SET MaxPathOptimal = ? WHERE ID = ?", false, bestFound)
+	t.Assert(err == nil)
+
+	ids := func(query string) map[int64]bool {
+		var search models.MazeSearchResponse
+		t.authGet(t.BaseUrl() + "/maze?limit=100&maxSize=4x3&" + query)
+		t.AssertOk()
+		json.Unmarshal(t.ResponseBody, &search)
+		res := map[int64]bool{}
+		for _, maze := range search.Items {
+			res[maze.ID] = true
+		}
+		return res
+	}
+	withExact, withoutExact := ids("hasSolution=true"), ids("hasSolution=false")
+	t.Assert(withExact[exact] && !withExact[bestFound])
+	t.Assert(!withoutExact[exact] && withoutExact[bestFound])
+
+	t.authGet(t.BaseUrl() + "/maze?hasSolution=maybe")
+	t.AssertStatus(400)
+}
+
+// TestShareShouldGrantAccess ...
+func (t *MazeTest) TestShareShouldGrantAccess() {
+	id := t.createMaze(validMazeWithSolution1)
+	path := fmt.Sprintf("/maze/%d", id)
+
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"sharee\", \"password\": \"12345\"}"))
+	t.AssertOk()
+	var login models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &login)
+	t.sendAs(login.Token, "GET", "/user/me", "")
+	var profile models.UserResponse
+	json.Unmarshal(t.ResponseBody, &profile)
+	sharePath := fmt.Sprintf("%s/shares/%d", path, profile.ID)
+
+	// private maze should not be disclosed
+	t.sendAs(login.Token, "GET", path, "")
+	t.AssertStatus(400)
+
+	// shared maze should be readable with read permission
+	t.sendAs(validAuth, "PATCH", path, "{\"visibility\": \"shared\"}")
+	t.AssertOk()
+	t.sendAs(validAuth, "PUT", sharePath, "{\"permission\": \"read\"}")
+	t.AssertOk()
+	var shares models.MazeShareListResponse
+	json.Unmarshal(t.ResponseBody, &shares)
+	t.AssertEqual(len(shares.Items), 1)
+	t.AssertEqual(shares.Items[0].Username, "sharee")
+
+	t.sendAs(login.Token, "GET", path + "/solution?steps=min", "")
+	t.AssertOk()
+	t.sendAs(login.Token, "GET", "/maze?shared=true", "")
+	var search models.MazeSearchResponse
+	json.Unmarshal(t.ResponseBody, &search)
+	t.AssertEqual(len(search.Items), 1)
+	t.sendAs(login.Token, "PATCH", path, "{}")
+	t.AssertStatus(403)
+
+	// edit permission should allow changes except visibility
+	t.sendAs(validAuth, "PUT", sharePath, "{\"permission\": \"edit\"}")
+	t.AssertOk()
+	t.sendAs(login.Token, "PATCH", path, "{}")
+	t.AssertOk()
+	t.sendAs(login.Token, "PATCH", path, "{\"visibility\": \"public\"}")
+	t.AssertStatus(403)
+	t.sendAs(login.Token, "DELETE", path, "")
+	t.AssertStatus(403)
+	t.sendAs(login.Token, "GET", path + "/shares", "")
+	t.AssertStatus(403)
+
+	// public maze should be listed and readable by all users
+	t.sendAs(validAuth, "PATCH", path, "{\"visibility\": \"public\"}")
+	t.AssertOk()
+	t.sendAs(validAuth, "DELETE", sharePath, "")
+	t.AssertOk()
+	t.sendAs(login.Token, "GET", path, "")
+	t.AssertOk()
+	t.sendAs(login.Token, "PATCH", path, "{}")
+	t.AssertStatus(403)
+	t.sendAs(login.Token, "GET", "/maze/public", "")
+	t.AssertOk()
+	json.Unmarshal(t.ResponseBody, &search)
+	t.Assert(len(search.Items) > 0)
+}
+
+// TestOrgShouldOwnMazes ...
+func (t *MazeTest) TestOrgShouldOwnMazes() {
+	t.sendAs(validAuth, "POST", "/org", "{\"name\": \"maze-team\"}")
+	t.AssertOk()
+	var org models.OrgResponse
+	json.Unmarshal(t.ResponseBody, &org)
+	t.AssertEqual(org.Item.Role, models.OrgRoleOwner)
+	orgPath := fmt.Sprintf("/org/%d", org.Item.ID)
+
+	maze := validMazeWithSolution1
+	maze.OrgID = org.Item.ID
+	id := t.createMaze(maze)
+	path := fmt.Sprintf("/maze/%d", id)
+
+	t.Post("/user", "application/json", strings.NewReader("{\"username\": \"member\", \"password\": \"12345\"}"))
+	t.AssertOk()
+	var login models.LoginResponse
+	json.Unmarshal(t.ResponseBody, &login)
+	t.sendAs(login.Token, "GET", "/user/me", "")
+	var profile models.UserResponse
+	json.Unmarshal(t.ResponseBody, &profile)
+	memberPath := fmt.Sprintf("%s/members/%d", orgPath, profile.ID)
+
+	// non-members should not access org mazes
+	t.sendAs(login.Token, "GET", path, "")
+	t.AssertStatus(400)
+	t.sendAs(login.Token, "GET", orgPath + "/members", "")
+	t.AssertStatus(400)
+
+	// viewers should read org mazes only
+	t.sendAs(validAuth, "PUT", memberPath, "{\"role\": \"viewer\"}")
+	t.AssertOk()
+	var members models.OrgMemberListResponse
+	json.Unmarshal(t.ResponseBody, &members)
+	t.AssertEqual(len(members.Items), 2)
+	t.sendAs(login.Token, "GET", path, "")
+	t.AssertOk()
+	t.sendAs(login.Token, "PATCH", path, "{}")
+	t.AssertStatus(403)
+	t.sendAs(login.Token, "POST", "/maze/generate", fmt.Sprintf("{\"entrance\": \"A1\", \"gridSize\": \"5x5\", \"orgId\": %d}", org.Item.ID))
+	t.AssertStatus(403)
+
+	// org mazes should be listed with the owner filter
+	t.sendAs(login.Token, "GET", fmt.Sprintf("/maze?owner=%d", org.Item.ID), "")
+	t.AssertOk()
+	var search models.MazeSearchResponse
+	json.Unmarshal(t.ResponseBody, &search)
+	t.AssertEqual(len(search.Items), 1)
+	t.AssertEqual(search.Items[0].OrgID, org.Item.ID)
+	t.sendAs(login.Token, "GET", "/maze?owner=me", "")
+	json.Unmarshal(t.ResponseBody, &search)
+	t.AssertEqual(len(search.Items), 0)
+
+	// editors should edit org mazes, owners only should delete them
+	t.sendAs(validAuth, "PUT", memberPath, "{\"role\": \"editor\"}")
+	t.AssertOk()
+	t.sendAs(login.Token, "PATCH", path, "{}")
+	t.AssertOk()
+	t.sendAs(login.Token, "DELETE", path, "")
+	t.AssertStatus(403)
+	t.sendAs(login.Token, "PUT", memberPath, "{\"role\": \"owner\"}")
+	t.AssertStatus(403)
+
+	// the last owner should not leave
+	t.sendAs(validAuth, "GET", "/user/me", "")
+	json.Unmarshal(t.ResponseBody, &profile)
+	t.sendAs(validAuth, "DELETE", fmt.Sprintf("%s/members/%d", orgPath, profile.ID), "")
+	t.AssertStatus(400)
+
+	// members should leave, org deletion should delete its mazes
+	t.sendAs(login.Token, "DELETE", memberPath, "")
+	t.AssertOk()
+	t.sendAs(login.Token, "GET", path, "")
+	t.AssertStatus(400)
+	t.sendAs(validAuth, "DELETE", orgPath, "")
+	t.AssertOk()
+	t.sendAs(validAuth, "GET", path, "")
+	t.AssertStatus(400)
+}
+
+func (t *MazeTest) createMaze(maze models.Maze) int64 {
+	t.postObject(t.BaseUrl()+"/maze", maze)
+	t.AssertOk()
+
+	var resp models.MazeResponse
+	json.Unmarshal(t.ResponseBody, &resp)
+	return resp.ID
+}
+
+func (t *MazeTest) sendObject(method, url string, obj any) {
+	data, _ := json.Marshal(obj)
+	req, _ := http.NewRequest(method, url, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer " + validAuth)
+	t.NewTestRequest(req).Send()
 }
 
 func (t *MazeTest) postObject(url string, obj any) {
@@ -179,6 +755,13 @@ func (t *MazeTest) postObject(url string, obj any) {
 }
 
 
+func (t *MazeTest) sendAs(token, method, path, body string) {
+	req, _ := http.NewRequest(method, t.BaseUrl() + path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Bearer " + token)
+	t.NewTestRequest(req).Send()
+}
+
 func (t *MazeTest) authGet(url string) {
 	req := t.GetCustom(url)
 	req.Header.Add("Authorization", "Bearer " + validAuth)
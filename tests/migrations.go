@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/revel/revel/testing"
+
+	"github.com/mkulish/mazes/app/migrations"
+)
+
+// MigrationTest contains schema migrations tests on a separate in-memory database
+type MigrationTest struct {
+	testing.TestSuite
+}
+
+// TestMigrationsShouldApplyAndRollBack ...
+func (t *MigrationTest) TestMigrationsShouldApplyAndRollBack() {
+	db, err := sql.Open("sqlite3", "file:migrationtest?mode=memory&cache=shared")
+	t.Assert(err == nil)
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	t.Assert(err == nil)
+	t.Assert(migrator.Latest() > 0)
+
+	version, err := migrator.Version()
+	t.Assert(err == nil)
+	t.AssertEqual(version, 0)
+
+	applied, err := migrator.Up(0)
+	t.Assert(err == nil)
+	t.AssertEqual(len(applied), migrator.Latest())
+
+	// should be a no-op on the latest version
+	applied, err = migrator.Up(0)
+	t.Assert(err == nil)
+	t.AssertEqual(len(applied), 0)
+
+	// should roll back to the empty schema and apply again
+	rolledBack, err := migrator.Down(migrator.Latest())
+	t.Assert(err == nil)
+	t.AssertEqual(len(rolledBack), migrator.Latest())
+	version, _ = migrator.Version()
+	t.AssertEqual(version, 0)
+
+	var tables int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'Maze'").Scan(&tables)
+	t.AssertEqual(tables, 0)
+
+	_, err = migrator.Up(0)
+	t.Assert(err == nil)
+	version, _ = migrator.Version()
+	t.AssertEqual(version, migrator.Latest())
+
+	// should refuse a newer schema
+	_, err = db.Exec("INSERT INTO schema_migrations (version, name, applied) VALUES (?, 'future', 0)", migrator.Latest() + 1)
+	t.Assert(err == nil)
+	t.Assert(migrator.Check() != nil)
+	_, err = migrator.Up(0)
+	t.Assert(err != nil)
+}
+
+// TestMigrationsShouldKeepLegacyData ...
+func (t *MigrationTest) TestMigrationsShouldKeepLegacyData() {
+	db, err := sql.Open("sqlite3", "file:migrationlegacytest?mode=memory&cache=shared")
+	t.Assert(err == nil)
+	defer db.Close()
+
+	// schema and rows created by gorp before migrations were introduced
+	_, err = db.Exec(`
+		CREATE TABLE "User" ("ID" integer not null primary key autoincrement, "Username" varchar(255), "HashedPassword" blob);
+		CREATE TABLE "Maze" ("ID" integer not null primary key autoincrement, "OwnerID" integer, "Entrance" varchar(255), "GridSize" varchar(255), "WallsStr" varchar(255), "MinPathStr" varchar(255), "MaxPathStr" varchar(255));
+		INSERT INTO "User" ("Username", "HashedPassword") VALUES ('legacy', x'0102'), ('other', x'0304');
+		INSERT INTO "Maze" ("OwnerID", "Entrance", "GridSize", "WallsStr", "MinPathStr", "MaxPathStr")
+			VALUES (1, 'A1', '8x4', 'C1,A2,C3', 'A1,B1,B2', 'A1,B1,B2,B3,B4'), (2, 'B1', '3x12', '', '', '')`)
+	t.Assert(err == nil)
+
+	migrator, err := migrations.New(db)
+	t.Assert(err == nil)
+	version, err := migrator.Version()
+	t.Assert(err == nil)
+	t.AssertEqual(version, 1)
+
+	_, err = migrator.Up(0)
+	t.Assert(err == nil)
+
+	var username, role, visibility string
+	var created, rows, cols, wallsCount, minPathLen, maxPathLen int
+	err = db.QueryRow("SELECT Username, Role, Created FROM User WHERE ID = 1").Scan(&username, &role, &created)
+	t.Assert(err == nil)
+	t.AssertEqual(username, "legacy")
+	t.AssertEqual(role, "user")
+	t.Assert(created > 0)
+
+	err = db.QueryRow("SELECT Visibility, Rows, Cols, WallsCount, MinPathLen, MaxPathLen FROM Maze WHERE ID = 1").
+		Scan(&visibility, &rows, &cols, &wallsCount, &minPathLen, &maxPathLen)
+	t.Assert(err == nil)
+	t.AssertEqual(visibility, "private")
+	t.AssertEqual([]int{rows, cols, wallsCount, minPathLen, maxPathLen}, []int{8, 4, 3, 2, 4})
+
+	// walls should be converted into bitset: C1 -> bit 2, A2 -> bit 4, C3 -> bit 10 of 8x4 grid
+	var walls string
+	var wallsBits []byte
+	err = db.QueryRow("SELECT WallsStr, WallsBits FROM Maze WHERE ID = 1").Scan(&walls, &wallsBits)
+	t.Assert(err == nil)
+	t.AssertEqual(walls, "")
+	t.AssertEqual(wallsBits, []byte{0x14, 0x04, 0, 0})
+
+	err = db.QueryRow("SELECT Rows, Cols, WallsCount, MaxPathLen FROM Maze WHERE ID = 2").Scan(&rows, &cols, &wallsCount, &maxPathLen)
+	t.Assert(err == nil)
+	t.AssertEqual([]int{rows, cols, wallsCount, maxPathLen}, []int{3, 12, 0, 0})
+
+	// should roll back to the legacy schema keeping the rows
+	_, err = migrator.Down(migrator.Latest() - 1)
+	t.Assert(err == nil)
+	columns, err := migrator.Columns("Maze")
+	t.Assert(err == nil)
+	t.AssertEqual(columns, []string{"ID", "OwnerID", "Entrance", "GridSize", "WallsStr", "MinPathStr", "MaxPathStr"})
+	columns, _ = migrator.Columns("User")
+	t.AssertEqual(columns, []string{"ID", "Username", "HashedPassword"})
+
+	var hashedPassword []byte
+	err = db.QueryRow("SELECT Username, HashedPassword FROM User WHERE ID = 2").Scan(&username, &hashedPassword)
+	t.Assert(err == nil)
+	t.AssertEqual(username, "other")
+	t.AssertEqual(hashedPassword, []byte{3, 4})
+	err = db.QueryRow("SELECT WallsStr FROM Maze WHERE ID = 1").Scan(&walls)
+	t.Assert(err == nil)
+	t.AssertEqual(walls, "C1,A2,C3")
+
+	_, err = migrator.Up(0)
+	t.Assert(err == nil)
+	version, _ = migrator.Version()
+	t.AssertEqual(version, migrator.Latest())
+}
+
+// TestMigrationsShouldRefuseUnknownSchema ...
+func (t *MigrationTest) TestMigrationsShouldRefuseUnknownSchema() {
+	db, err := sql.Open("sqlite3", "file:migrationunknowntest?mode=memory&cache=shared")
+	t.Assert(err == nil)
+	defer db.Close()
+
+	// unversioned schema with columns added after the initial one
+	_, err = db.Exec(`
+		CREATE TABLE "User" ("ID" integer not null primary key autoincrement, "Username" varchar(255), "HashedPassword" blob, "Role" varchar(255));
+		CREATE TABLE "Maze" ("ID" integer not null primary key autoincrement, "OwnerID" integer, "Entrance" varchar(255), "GridSize" varchar(255), "WallsStr" varchar(255), "MinPathStr" varchar(255), "MaxPathStr" varchar(255))`)
+	t.Assert(err == nil)
+
+	migrator, err := migrations.New(db)
+	t.Assert(err == nil)
+	_, err = migrator.Up(0)
+	t.Assert(err != nil)
+
+	var tables int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'MazeShare'").Scan(&tables)
+	t.AssertEqual(tables, 0)
+}
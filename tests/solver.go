@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/revel/revel/testing"
+
+	"github.com/mkulish/mazes/app/models"
+	"github.com/mkulish/mazes/app/services"
+)
+
+const (
+	// number of random grids checked by each property test
+	solverSamples = 500
+	// random grids seed, fixed for reproducible failures
+	solverSeed = 42
+)
+
+// SolverTest contains property-based tests for maze solvers
+// checked against brute-force reference on random grids
+type SolverTest struct {
+	testing.TestSuite
+}
+
+// TestShortestPathShouldMatchReference ...
+func (t *SolverTest) TestShortestPathShouldMatchReference() {
+	rnd := rand.New(rand.NewSource(solverSeed))
+	for i := 0; i < solverSamples; i++ {
+		maze := randomMaze(rnd)
+		min, _ := referencePaths(maze)
+
+		for _, name := range services.Algorithms(services.MinSteps) {
+			path, optimal, err := services.FindSolver(services.MinSteps, name).Solve(&maze)
+			if min < 0 {
+				t.Assertf(err != nil, "%s: expected no solution for %v, got %v", name, maze, path)
+				continue
+			}
+			t.Assertf(err == nil, "%s: unexpected error for %v: %v", name, maze, err)
+			t.assertPath(maze, path)
+			if optimal {
+				t.Assertf(len(path) - 1 == min, "%s: expected %d steps for %v, got %v", name, min, maze, path)
+			}
+		}
+	}
+}
+
+// TestLongestPathShouldMatchReference ...
+func (t *SolverTest) TestLongestPathShouldMatchReference() {
+	rnd := rand.New(rand.NewSource(solverSeed))
+	for i := 0; i < solverSamples; i++ {
+		maze := randomMaze(rnd)
+		_, max := referencePaths(maze)
+
+		path, optimal, err := services.LongestPath(&maze, services.Budget{})
+		if err != nil {
+			// multiple exits are checked by validation tests
+			continue
+		}
+		t.assertPath(maze, path)
+		t.Assert(optimal)
+		t.Assertf(len(path) - 1 == max, "expected %d steps for %v, got %v", max, maze, path)
+	}
+}
+
+// TestLongestPathShouldMatchKnownOnLargeGrids ...
+func (t *SolverTest) TestLongestPathShouldMatchKnownOnLargeGrids() {
+	// grids above memoCells open cells with the only exit A<rows>, the known longest paths
+	// go through all open cells above the exit row (or all but one by the cells colouring)
+	var rooms []string
+	for x := 0; x < 26; x++ {
+		rooms = append(rooms, models.CellName(x, 8), models.CellName(x + 1, 17))
+	}
+	grids := []struct {
+		maze  models.Maze
+		steps int
+	}{
+		{exitMaze(9, 9, "A1"), 8 * 9},
+		// E4 and A8 are of the same colour, a path between them skips a cell
+		{exitMaze(9, 9, "E4"), 8 * 9 - 1},
+		{exitMaze(27, 27, "A1"), 26 * 27},
+		{exitMaze(27, 27, "A1", rooms...), 26 * 27 - len(rooms)},
+		{exitMaze(99, 27, "A1"), 98 * 27},
+	}
+
+	for _, grid := range grids {
+		path, optimal, err := services.LongestPath(&grid.maze, services.Budget{})
+		t.Assertf(err == nil, "unexpected error for %s from %s: %v", grid.maze.GridSize, grid.maze.Entrance, err)
+		t.assertPath(grid.maze, path)
+		t.Assert(optimal)
+		t.Assertf(len(path) - 1 == grid.steps, "expected %d steps for %s from %s, got %d",
+			grid.steps, grid.maze.GridSize, grid.maze.Entrance, len(path) - 1)
+	}
+}
+
+// TestLongestPathShouldReturnBestFoundOnBudget ...
+func (t *SolverTest) TestLongestPathShouldReturnBestFoundOnBudget() {
+	maze := exitMaze(9, 9, "A1")
+	min, _, err := services.FindSolver(services.MinSteps, "bfs").Solve(&maze)
+	t.Assert(err == nil)
+
+	for _, nodes := range []int{1, 10, 100} {
+		path, optimal, err := services.LongestPath(&maze, services.Budget{Nodes: nodes})
+		t.Assertf(err == nil, "unexpected error for %d nodes: %v", nodes, err)
+		t.Assertf(!optimal, "expected best found path for %d nodes", nodes)
+		// the best found path is at least the shortest one
+		t.assertPath(maze, path)
+		t.Assertf(len(path) >= len(min), "expected at least %d steps for %d nodes, got %v", len(min) - 1, nodes, path)
+	}
+}
+
+// TestSolveMazeShouldDetectExitsConsistently ...
+func (t *SolverTest) TestSolveMazeShouldDetectExitsConsistently() {
+	rnd := rand.New(rand.NewSource(solverSeed))
+	for i := 0; i < solverSamples; i++ {
+		maze := randomMaze(rnd)
+		reach := services.AnalyzeReachability(&maze)
+
+		_, _, minErr := services.SolveMaze(&maze, true)
+		_, _, maxErr := services.SolveMaze(&maze, false)
+		t.Assertf((minErr == nil) == (len(reach.Exits) == 1), "min: unexpected result for %v: %v", maze, minErr)
+		t.Assertf((maxErr == nil) == (len(reach.Exits) == 1), "max: unexpected result for %v: %v", maze, maxErr)
+	}
+}
+
+// assertPath checks the path is a simple path from the entrance to the exit row
+func (t *SolverTest) assertPath(maze models.Maze, path []string) {
+	t.Assertf(len(path) > 0 && path[0] == maze.Entrance, "path %v should start at %s", path, maze.Entrance)
+
+	walls := make(map[string]bool, len(maze.Walls))
+	for _, wall := range maze.Walls {
+		walls[wall] = true
+	}
+	rows, _ := models.ParseGridSize(maze.GridSize)
+
+	visited := map[string]bool{}
+	for i, cell := range path {
+		t.Assertf(!walls[cell], "path %v goes through the wall %s", path, cell)
+		t.Assertf(!visited[cell], "path %v visits %s twice", path, cell)
+		visited[cell] = true
+
+		x, y := models.CellCoords(cell)
+		if i > 0 {
+			px, py := models.CellCoords(path[i - 1])
+			t.Assertf(abs(x - px) + abs(y - py) == 1, "path %v has a gap at %s", path, cell)
+		}
+		t.Assertf((y == rows - 1) == (i == len(path) - 1), "path %v should end on the exit row", path)
+	}
+}
+
+// randomMaze returns up to 5x5 grid with the entrance on the first row
+func randomMaze(rnd *rand.Rand) models.Maze {
+	cols, rows := 1 + rnd.Intn(5), 2 + rnd.Intn(4)
+	maze := models.Maze{
+		Entrance: cellName(rnd.Intn(cols), 0),
+		GridSize: fmt.Sprintf("%dx%d", rows, cols),
+		Walls:    []string{},
+	}
+
+	density := rnd.Float64() * 0.5
+	for x := 0; x < cols; x++ {
+		for y := 0; y < rows; y++ {
+			if cell := cellName(x, y); cell != maze.Entrance && rnd.Float64() < density {
+				maze.Walls = append(maze.Walls, cell)
+			}
+		}
+	}
+	return maze
+}
+
+// exitMaze returns the grid with the walls and the only exit in the bottom-left cell
+func exitMaze(rows, cols int, entrance string, walls ...string) models.Maze {
+	maze := models.Maze{
+		Entrance: entrance,
+		GridSize: fmt.Sprintf("%dx%d", rows, cols),
+		Walls:    append([]string{}, walls...),
+	}
+	for x := 1; x < cols; x++ {
+		maze.Walls = append(maze.Walls, models.CellName(x, rows - 1))
+	}
+	return maze
+}
+
+// referencePaths enumerates all simple paths to the exit row, returns min and max steps (-1 if none)
+func referencePaths(maze models.Maze) (int, int) {
+	rows, _ := strconv.Atoi(maze.GridSize[:1])
+	cols, _ := strconv.Atoi(maze.GridSize[2:])
+
+	blocked := map[string]bool{}
+	for _, wall := range maze.Walls {
+		blocked[wall] = true
+	}
+
+	min, max := -1, -1
+	var walk func(x, y, steps int)
+	walk = func(x, y, steps int) {
+		if y == rows - 1 {
+			if min < 0 || steps < min {
+				min = steps
+			}
+			if steps > max {
+				max = steps
+			}
+			return
+		}
+
+		for _, d := range [][2]int{{-1, 0}, {0, -1}, {1, 0}, {0, 1}} {
+			nx, ny := x + d[0], y + d[1]
+			if nx < 0 || nx >= cols || ny < 0 || ny >= rows || blocked[cellName(nx, ny)] {
+				continue
+			}
+			blocked[cellName(nx, ny)] = true
+			walk(nx, ny, steps + 1)
+			blocked[cellName(nx, ny)] = false
+		}
+	}
+
+	x, y := cellCoords(maze.Entrance)
+	blocked[maze.Entrance] = true
+	walk(x, y, 0)
+	return min, max
+}
+
+func cellName(x, y int) string {
+	return fmt.Sprintf("%c%d", rune('A' + x), y + 1)
+}
+
+func cellCoords(cell string) (int, int) {
+	y, _ := strconv.Atoi(cell[1:])
+	return int(cell[0] - 'A'), y - 1
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}